@@ -12,30 +12,33 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+	"github.com/ThomasK81/TEItoCEX/tokenizer"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // ReportJSON is a struct for exporting to JSON
 type ReportJSON struct {
-	Nodecount   int           `json:"nodeCount"`
-	Greekwords  int           `json:"greekWords"`
-	Latinwords  int           `json:"latinWords"`
-	Arabicwords int           `json:"arabicwords"`
-	Catalog     []JSONCatalog `json:"catalog"`
+	Nodecount   int            `json:"nodeCount"`
+	Greekwords  int            `json:"greekWords"`
+	Latinwords  int            `json:"latinWords"`
+	Arabicwords int            `json:"arabicwords"`
+	Scripts     map[string]int `json:"scripts"`
+	Catalog     []JSONCatalog  `json:"catalog"`
 }
 
 // JSONCatalog is a struct for exporting to JSON. It is a sub-struct to ReportJSON.
 type JSONCatalog struct {
-	URN       string `json:"urn"`
-	GroupName string `json:"group_name"`
-	WorkName  string `json:"work_name"`
-	Language  string `json:"language"`
-	WordCount int    `json:"wordcount"`
-	Scaife    string `json:"scaife"`
+	URN       string         `json:"urn"`
+	GroupName string         `json:"group_name"`
+	WorkName  string         `json:"work_name"`
+	Language  string         `json:"language"`
+	WordCount int            `json:"wordcount"`
+	Scripts   map[string]int `json:"scripts"`
+	Scaife    string         `json:"scaife"`
 }
 
 //CTSCatalog is the main container for CTS catalog data in the format expected by CEX, but in a way that it can integrated into a number of ways.
@@ -48,6 +51,8 @@ type CTSCatalog struct {
 	ExemplarLabel  []string    `json:"exemplar_label"`
 	Online         []string    `json:"online"`
 	Language       []string    `json:"language"`
+	Description    []string    `json:"description"`
+	VersionType    []string    `json:"version_type"`
 	Contributors   []JSONContr `json:"contributions"`
 }
 
@@ -92,12 +97,6 @@ type OAIDCRecord struct {
 	Publisher string `xml:"dc:publisher"`
 }
 
-//Metadata container for Xpath metadata
-type Metadata struct {
-	Xpath string
-	Kind  string
-}
-
 //XPathInfo container for Xpath metadata
 type XPathInfo struct {
 	XPathInfo string `xml:"replacementPattern,attr"`
@@ -107,6 +106,8 @@ type XPathInfo struct {
 //LangInfo container for language metadata
 type LangInfo struct {
 	Language string `xml:"ident,attr"`
+	P4ID     string `xml:"id,attr"`
+	Name     string `xml:",chardata"`
 }
 
 //OGLHeader container for header information
@@ -124,1516 +125,437 @@ type Contributor struct {
 	PersName []string `xml:"persName"`
 }
 
-//SmallestNode container for smallest CTS node
-type SmallestNode struct {
-	InnerXML string `xml:",innerxml"`
-	Number   string `xml:"n,attr"`
-}
-
-//StartTEI1Direct is one of many parsing containers.
-type StartTEI1Direct struct {
-	Node []SmallestNode `xml:"text>body>div"`
-}
-
-//StartTEI1p is one of many parsing containers.
-type StartTEI1p struct {
-	Node []SmallestNode `xml:"text>body>div>p"`
-}
-
-//StartTEI1pseg is one of many parsing containers.
-type StartTEI1pseg struct {
-	Node []SmallestNode `xml:"text>body>div>p>seg"`
-}
-
-//StartTEI1 is one of many parsing containers.
-type StartTEI1 struct {
-	Node []SmallestNode `xml:"text>body>div>div"`
-}
-
-//StartTEI1Late is one of many parsing containers.
-type StartTEI1Late struct {
-	Node []SmallestNode `xml:"text>body>div>div>div"`
-}
-
-//TEI3n2 is one of many parsing containers.
-type TEI3n2 struct {
-	Node   []SmallestNode `xml:"div"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI3n3 is one of many parsing containers.
-type TEI3n3 struct {
-	Node   []TEI3n2 `xml:"div"`
-	Number string   `xml:"n,attr"`
-}
-
-//StartTEI3 is one of many parsing containers.
-type StartTEI3 struct {
-	Node []TEI3n3 `xml:"text>body>div>div"`
-}
-
-//TEI3n2p is one of many parsing containers.
-type TEI3n2p struct {
-	Node   []SmallestNode `xml:"p"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI3n3p is one of many parsing containers.
-type TEI3n3p struct {
-	Node   []TEI3n2p `xml:"div"`
-	Number string    `xml:"n,attr"`
-}
-
-//StartTEI3p is one of many parsing containers.
-type StartTEI3p struct {
-	Node []TEI3n3p `xml:"text>body>div>div"`
-}
-
-//TEI4n2p is one of many parsing containers.
-type TEI4n2p struct {
-	Node   []SmallestNode `xml:"p"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI4n3p is one of many parsing containers.
-type TEI4n3p struct {
-	Node   []TEI4n2p `xml:"div"`
-	Number string    `xml:"n,attr"`
-}
-
-//TEI4n4p is one of many parsing containers.
-type TEI4n4p struct {
-	Node   []TEI4n3p `xml:"div"`
-	Number string    `xml:"n,attr"`
-}
-
-//StartTEI4p is one of many parsing containers.
-type StartTEI4p struct {
-	Node []TEI4n4p `xml:"text>body>div>div"`
-}
-
-//TEI4n2div is one of many parsing containers.
-type TEI4n2div struct {
-	Node   []SmallestNode `xml:"div"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI4n3div is one of many parsing containers.
-type TEI4n3div struct {
-	Node   []TEI4n2div `xml:"div"`
-	Number string      `xml:"n,attr"`
-}
-
-//TEI4n4div is one of many parsing containers.
-type TEI4n4div struct {
-	Node   []TEI4n3div `xml:"div"`
-	Number string      `xml:"n,attr"`
-}
-
-//StartTEI4div is one of many parsing containers.
-type StartTEI4div struct {
-	Node []TEI4n4div `xml:"text>body>div>div"`
-}
-
-//StartTEI2p is one of many parsing containers.
-type StartTEI2p struct {
-	Node []TEI3n2p `xml:"text>body>div>div"`
-}
-
-//TEI2n2ab is one of many parsing containers.
-type TEI2n2ab struct {
-	Node   []SmallestNode `xml:"ab"`
-	Number string         `xml:"n,attr"`
-}
-
-//StartTEI2ab is one of many parsing containers.
-type StartTEI2ab struct {
-	Node []TEI2n2ab `xml:"text>body>div>div"`
-}
-
-//TEI2n2lgl is one of many parsing containers.
-type TEI2n2lgl struct {
-	Node   []SmallestNode `xml:"lg>l"`
-	Number string         `xml:"n,attr"`
-}
-
-//StartTEI2lgl is one of many parsing containers.
-type StartTEI2lgl struct {
-	Node []TEI2n2lgl `xml:"text>body>div>div"`
-}
-
-//TEI3n2cit is one of many parsing containers.
-type TEI3n2cit struct {
-	Node   []SmallestNode `xml:"cit"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI3n3cit is one of many parsing containers.
-type TEI3n3cit struct {
-	Node   []TEI3n2cit `xml:"p"`
-	Number string      `xml:"n,attr"`
-}
-
-//StartTEI3cit is one of many parsing containers.
-type StartTEI3cit struct {
-	Node []TEI3n3cit `xml:"text>body>div>div"`
-}
-
-//TEI3n3divcit is one of many parsing containers.
-type TEI3n3divcit struct {
-	Node   []TEI3n2cit `xml:"div"`
-	Number string      `xml:"n,attr"`
-}
-
-//StartTEI3divcit is one of many parsing containers.
-type StartTEI3divcit struct {
-	Node []TEI3n3divcit `xml:"text>body>div>div"`
-}
-
-//StartTEI2 is one of many parsing containers.
-type StartTEI2 struct {
-	Node []TEI3n2 `xml:"text>body>div>div"`
-}
-
-//StartTEI2direct is one of many parsing containers.
-type StartTEI2direct struct {
-	Node []TEI3n2 `xml:"text>body>div"`
-}
-
-//StartTEI1Poem is one of many parsing containers.
-type StartTEI1Poem struct {
-	Node []SmallestNode `xml:"text>body>div>l"`
-}
-
-//TEI2Poemn2 is one of many parsing containers.
-type TEI2Poemn2 struct {
-	Node   []SmallestNode `xml:"l"`
-	Number string         `xml:"n,attr"`
-}
-
-//StartTEI2Poem is one of many parsing containers.
-type StartTEI2Poem struct {
-	Node []TEI2Poemn2 `xml:"text>body>div>div"`
-}
-
-//TEI3n2DirectNumbered is one of many parsing containers.
-type TEI3n2DirectNumbered struct {
-	Node   []SmallestNode `xml:"div3"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI3n3DirectNumbered is one of many parsing containers.
-type TEI3n3DirectNumbered struct {
-	Node   []TEI3n2DirectNumbered `xml:"div2"`
-	Number string                 `xml:"n,attr"`
-}
-
-//StartTEI3DirectNumbered is one of many parsing containers.
-type StartTEI3DirectNumbered struct {
-	Node []TEI3n3DirectNumbered `xml:"text>body>div1"`
-}
-
-//TEI3n2poem is one of many parsing containers.
-type TEI3n2poem struct {
-	Node   []SmallestNode `xml:"l"`
-	Number string         `xml:"n,attr"`
-}
-
-//TEI3n3poem is one of many parsing containers.
-type TEI3n3poem struct {
-	Node   []TEI3n2poem `xml:"div"`
-	Number string       `xml:"n,attr"`
-}
-
-//StartTEI3poem is one of many parsing containers.
-type StartTEI3poem struct {
-	Node []TEI3n3poem `xml:"text>body>div>div"`
-}
-
-//QSmallestNode is one of many parsing containers.
-type QSmallestNode struct {
-	InnerXML string `xml:",innerxml"`
-	Number   string `xml:"n,attr"`
-}
-
-//QueryTEI2 is one of many parsing containers.
-type QueryTEI2 struct {
-	Node []QSmallestNode `xml:"text>body>div>div"`
-}
-
-//QueryTEI3n3 is one of many parsing containers.
-type QueryTEI3n3 struct {
-	Node   []QSmallestNode `xml:"div"`
-	Number string          `xml:"n,attr"`
-}
-
-//QueryTEI3 is one of many parsing containers.
-type QueryTEI3 struct {
-	Node []QueryTEI3n3 `xml:"text>body>div>div"`
-}
-
-//QueryTEI1 is one of many parsing containers.
-type QueryTEI1 struct {
-	Node []QSmallestNode `xml:"text>body>div"`
-}
-
-//QueryTEI1p is one of many parsing containers.
-type QueryTEI1p struct {
-	Node []QSmallestNode `xml:"text>body>div>p"`
-}
-
-//QSmallestNodeDiv is one of many parsing containers.
-type QSmallestNodeDiv struct {
-	InnerXML string `xml:",innerxml"`
-}
-
-//QueryTEI1div is one of many parsing containers.
-type QueryTEI1div struct {
-	Node []QSmallestNodeDiv `xml:"text>body>div>div"`
-}
-
-//QueryTEI0 is one of many parsing containers.
-type QueryTEI0 struct {
-	Node []QSmallestNode `xml:"text>body"`
-}
-
-//QueryInfo is one of many parsing containers.
-type QueryInfo struct {
-	InnerXML string `xml:",innerxml"`
-	Number   string `xml:"n,attr"`
-}
-
 func main() {
 	scheme := make(map[string]int)
-	tempscheme := ""
-	outputFile := ""
-	switch len(os.Args) {
-	case 1:
-		fmt.Println("Usage: CTSExtract [output-filename] [optionally: -CSV|JSON|XML|HTML]")
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
 		os.Exit(3)
-	case 2, 3:
-		outputFile = os.Args[1]
-	default:
-		fmt.Println("Usage: CTSExtract [output-filename] [optionally: -CSV|JSON|XML|HTML]")
+	}
+	if os.Args[1] == "query" {
+		if err := runQuery(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		return
+	}
+	outputFile := os.Args[1]
+	mode, options, err := parseArgs(os.Args[2:])
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println(usage)
 		os.Exit(3)
 	}
-	basereg := regexp.MustCompile(`urn:\p{L}+:\p{L}+:`)
-	tagsRegExp := regexp.MustCompile(`<[/]*[^>]*>`)
-	greekWordRegExp := regexp.MustCompile(`\p{Greek}+`)
-	latinWordRegExp := regexp.MustCompile(`\p{Latin}+`)
-	arabicWordRegExp := regexp.MustCompile(`\p{Arabic}+`)
 
 	var querystrings []string
 	var identifiers []string
 	var texts []string
 	var unstrippedTexts []string
-	var greekwordcounts []string
-	var latinwordcounts []string
-	var arabicwordcounts []string
+	wordcounts := newWordCounts(options.scripts())
 	var ctscatalog CTSCatalog
+	var citationtree CitationTree
+	var notes NoteCollection
+	var apparatus []ApparatusEntry
+	var entities EntityCollection
+	var speakers SpeakerCollection
+	var speakernames []string
+	var passagelanguages []string
 
 	filecount := 0
-	greekwords := 0
-	latinwords := 0
-	arabicwords := 0
 	noxpath := []string{}
+	policy := options.elementPolicy()
+	breaks := options.breakElements()
 	xmlFiles := checkExt(".xml")
+	var library CEXLibrary
+	if options.Input != "" {
+		// the catalog and passages come from a CEX file instead of the TEI files
+		xmlFiles = nil
+		library, err = readCEX(options.Input)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(3)
+		}
+		ctscatalog = library.Catalog
+		identifiers = library.Identifiers
+		for _, v := range library.Texts {
+			texts = append(texts, options.normalizeText(v))
+		}
+		unstrippedTexts = texts
+		citationtree = citationTreeFromPassages(ctscatalog, identifiers)
+		versions := make(map[string]bool)
+		for _, v := range ctscatalog.URN {
+			versions[v] = true
+		}
+		for i, v := range texts {
+			// exemplars such as tokenized ones repeat the words of their version
+			if passage, err := ctsurn.Parse(identifiers[i]); err == nil && passage.Exemplar != "" && versions[passage.Text().WithExemplar("").String()] {
+				wordcounts.skip()
+			} else {
+				wordcounts.add(v)
+			}
+			speakernames = append(speakernames, "")
+		}
+		passagelanguages = catalogLanguages(ctscatalog, identifiers)
+		fmt.Println("Read", len(identifiers), "passages of", len(ctscatalog.URN), "texts from", options.Input)
+	}
+	capitains := newCapitainsIndex()
 	for _, file := range xmlFiles {
-		basestr := "urn:cts:greekLit:"
 		xmlFile, err := os.Open(file)
 		if err != nil {
 			fmt.Println(err)
 		}
 		//fmt.Println(file)
 		byteValue, _ := ioutil.ReadAll(xmlFile)
-		match := basereg.FindStringSubmatch(string(byteValue))
-		if len(match) > 0 {
-			basestr = match[0]
-		}
+		byteValue = expandDeclaredEntities(byteValue, file)
+		byteValue = options.normalizeDocument(byteValue)
 		var headerinfo OGLHeader
-		err = xml.Unmarshal(byteValue, &headerinfo)
+		err = newLenientDecoder(byteValue).Decode(&headerinfo)
+		check(err)
+		root, err := parseXMLTree(byteValue)
 		check(err)
-		if len(headerinfo.RefPattern) == 0 {
+		p4 := options.P4 || (root.Name == "TEI.2" && len(headerinfo.RefPattern) == 0)
+		milestonesOnly := options.Milestone != "" && len(headerinfo.RefPattern) == 0 && !p4
+		if len(headerinfo.RefPattern) == 0 && !p4 && !milestonesOnly {
 			noxpath = append(noxpath, path.Base(file))
 		}
-		if len(headerinfo.RefPattern) > 0 {
-			var meta []Metadata
-			for i := range headerinfo.RefPattern {
-				meta = append(meta, Metadata{Xpath: headerinfo.RefPattern[i].XPathInfo, Kind: headerinfo.RefPattern[i].XPathWhat})
-			}
-			sort.Slice(meta, func(i int, j int) bool {
-				return len(meta[i].Xpath) < len(meta[j].Xpath)
-			})
-			querystring := meta[len(meta)-1].Xpath
-			whatkind := []string{}
-			for i := range meta {
-				whatkind = append(whatkind, meta[i].Kind)
+		if len(headerinfo.RefPattern) > 0 || p4 || milestonesOnly {
+			// a file with several version divs, each with its own URN, holds several versions; nil stands for the whole file
+			versions := separateVersions(root)
+			if versions == nil {
+				versions = []*XMLNode{nil}
 			}
-			languages := []string{}
-			for i := range headerinfo.Languages {
-				languages = append(languages, headerinfo.Languages[i].Language)
-			}
-			language := strings.Join(languages, ",")
-			kind := strings.Join(whatkind, ",")
-			querystring = strings.Replace(querystring, "#xpath(", "", -1)
-			querystring = strings.Replace(querystring, ")", "", -1)
-			urn := strings.Replace(path.Base(file), ".xml", "", -1)
-			urn = basestr + urn
-			ctscatalog.URN = append(ctscatalog.URN, urn)
-			ctscatalog.CitationScheme = append(ctscatalog.CitationScheme, kind)
-			group := strings.Join(headerinfo.Author, ",")
-			group = strings.Replace(group, "\n", " ", -1)
-			group = tagsRegExp.ReplaceAllString(group, "")
-			group = strings.TrimSpace(group)
-			ctscatalog.GroupName = append(ctscatalog.GroupName, group)
-			worktitle := strings.Join(headerinfo.Title, ",")
-			worktitle = strings.Replace(worktitle, "\n", " ", -1)
-			worktitle = tagsRegExp.ReplaceAllString(worktitle, "")
-			worktitle = strings.TrimSpace(worktitle)
-			ctscatalog.WorkTitle = append(ctscatalog.WorkTitle, worktitle)
-			ctscatalog.VersionLabel = append(ctscatalog.VersionLabel, "")
-			ctscatalog.ExemplarLabel = append(ctscatalog.ExemplarLabel, "")
-			ctscatalog.Online = append(ctscatalog.Online, "True")
-			ctscatalog.Language = append(ctscatalog.Language, language)
-			// adding contributors
-			contribution := JSONContr{}
-			for _, v := range headerinfo.Contributors {
-				tempContr := JSONContrs{}
-				tempContr.Resp = v.Resp
-				tempContr.PersName = v.PersName
-				contribution.Contribution = append(contribution.Contribution, tempContr)
-			}
-			ctscatalog.Contributors = append(ctscatalog.Contributors, contribution)
-			// debugging end
-			switch {
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']/tei:div[@n='$3']/tei:p[@n='$4']":
-				tempscheme = "1"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI4p
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							for l := range data.Node[i].Node[j].Node[k].Node {
-								id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number, data.Node[i].Node[j].Node[k].Node[l].Number}
-								identifier := strings.Join(id, ".")
-								identifier = strings.Join([]string{urn, identifier}, ":")
-								text := data.Node[i].Node[j].Node[k].Node[l].InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
+			read := false
+			for _, version := range versions {
+				// errors of one version do not carry over to the next
+				var err error
+				var passages []CitedNode
+				var unknown []string
+				whatkind := []string{}
+				schemename := ""
+				switch {
+				case milestonesOnly:
+					schemename = "milestones"
+					if version != nil {
+						passages = append(passages, CitedNode{Node: version, Leaf: true})
+						break
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div//tei:div[@n='$1']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div//tei:div[@n=\\'$1\\']":
-				tempscheme = "2"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI1
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
-							break
-						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "div" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								identifier := strings.Join([]string{urn, info.Number}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
+					for _, text := range childrenNamed(root, "text") {
+						for _, body := range childrenNamed(text, "body") {
+							passages = append(passages, CitedNode{Node: body, Leaf: true})
 						}
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div//tei:div[@subtype='fragment'][@n='$1']":
-				tempscheme = "3"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI1div
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
-							break
-						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "div" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								identifier := strings.Join([]string{urn, info.Number}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
+				case p4:
+					units := p4Units(root)
+					var uncited []string
+					passages, uncited = resolveNumberedDivs(root, byteValue, units, version)
+					if len(uncited) > 0 {
+						fmt.Println("Warning:", path.Base(file), "has text outside the divisions of", strings.Join(uncited, ", "), "which is not cited, as a division numbered 0 exists.")
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:p//tei:l[@n='$1']":
-				tempscheme = "4"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI1p
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
-							break
-						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "l" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								identifier := strings.Join([]string{urn, info.Number}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
+					whatkind = numberedDivScheme(passages)
+					if whatkind == nil {
+						// no numbered divisions, as if the file had no XPath
+						if len(noxpath) == 0 || noxpath[len(noxpath)-1] != path.Base(file) {
+							noxpath = append(noxpath, path.Base(file))
 						}
+						continue
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div//tei:l[@n='$1']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div//tei:l[@n=\\'$1\\']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:sp/tei:l[@n='$1']":
-				tempscheme = "5"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI1
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
-							break
-						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "l" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								identifier := strings.Join([]string{urn, info.Number}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
+					schemename = "TEI P4 numbered divisions"
+					scope := root
+					if version != nil {
+						scope = version
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body//tei:l[@n=\\'$1\\']":
-				tempscheme = "6"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI0
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
-							break
-						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "l" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								identifier := strings.Join([]string{urn, info.Number}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
+					split, missing := milestoneUnits(scope, units, len(whatkind))
+					if len(missing) > 0 {
+						fmt.Println("Warning:", path.Base(file), "declares the units", strings.Join(missing, ", "), "but marks them neither by divisions nor by milestones.")
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']//tei:div[@n='$2']":
-				tempscheme = "7"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI2
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					ID2 := data.Node[i].Number
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
+					for _, unit := range split {
+						if unit == options.Milestone {
+							// split below by -Milestone
 							break
 						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "div" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								id := []string{ID2, info.Number}
-								identifier := strings.Join(id, ".")
-								identifier = strings.Join([]string{urn, identifier}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
-					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']//tei:div[@n='$3']":
-				tempscheme = "8"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI3
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					ID2 := data.Node[i].Number
-					for j := range data.Node[i].Node {
-						ID3 := data.Node[i].Node[j].Number
-						r := strings.NewReader(data.Node[i].Node[j].InnerXML)
-						decoder := xml.NewDecoder(r)
-						for {
-							t, _ := decoder.Token()
-							if t == nil {
-								break
-							}
-							switch se := t.(type) {
-							case xml.StartElement:
-								if se.Name.Local == "div" {
-									var info QueryInfo
-									err = decoder.DecodeElement(&info, &se)
-									check(err)
-									id := []string{ID2, ID3, info.Number}
-									identifier := strings.Join(id, ".")
-									identifier = strings.Join([]string{urn, identifier}, ":")
-									text := info.InnerXML
-									unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-									text = stringcleaning(text)
-
-									words := greekWordRegExp.FindAllString(text, -1)
-									latinword := latinWordRegExp.FindAllString(text, -1)
-									arabicword := arabicWordRegExp.FindAllString(text, -1)
-									greekwords = greekwords + len(words)
-									wordcount := strconv.Itoa(len(words))
-									latinwords = latinwords + len(latinword)
-									arabicwords = arabicwords + len(arabicword)
-									latinwordcount := strconv.Itoa(len(latinword))
-									arabicwordcount := strconv.Itoa(len(arabicword))
-									latinwordcounts = append(latinwordcounts, latinwordcount)
-									arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-									identifiers = append(identifiers, identifier)
-									texts = append(texts, text)
-									greekwordcounts = append(greekwordcounts, wordcount)
-								}
-							}
-						}
-					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']//tei:l[@n='$2']":
-				tempscheme = "9"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data QueryTEI2
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					ID2 := data.Node[i].Number
-					r := strings.NewReader(data.Node[i].InnerXML)
-					decoder := xml.NewDecoder(r)
-					for {
-						t, _ := decoder.Token()
-						if t == nil {
+						passages, err = splitPassagesAtMilestones(passages, byteValue, unit)
+						if err != nil {
 							break
 						}
-						switch se := t.(type) {
-						case xml.StartElement:
-							if se.Name.Local == "l" {
-								var info QueryInfo
-								err = decoder.DecodeElement(&info, &se)
-								check(err)
-								id := []string{ID2, info.Number}
-								identifier := strings.Join(id, ".")
-								identifier = strings.Join([]string{urn, identifier}, ":")
-								text := info.InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
+						whatkind = append(whatkind, unit)
+						schemename = schemename + " split at " + unit
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']/tei:p[@n='$3']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@n=\\'$1\\']/tei:div[@n=\\'$2\\']/tei:p[@n=\\'$3\\']":
-				tempscheme = "A"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI3p
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number}
-							identifier := strings.Join(id, ".")
-							identifier = strings.Join([]string{urn, identifier}, ":")
-							text := data.Node[i].Node[j].Node[k].InnerXML
-							unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-							text = stringcleaning(text)
-
-							words := greekWordRegExp.FindAllString(text, -1)
-							latinword := latinWordRegExp.FindAllString(text, -1)
-							arabicword := arabicWordRegExp.FindAllString(text, -1)
-							greekwords = greekwords + len(words)
-							wordcount := strconv.Itoa(len(words))
-							latinwords = latinwords + len(latinword)
-							arabicwords = arabicwords + len(arabicword)
-							latinwordcount := strconv.Itoa(len(latinword))
-							arabicwordcount := strconv.Itoa(len(arabicword))
-							latinwordcounts = append(latinwordcounts, latinwordcount)
-							arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-							identifiers = append(identifiers, identifier)
-							texts = append(texts, text)
-							greekwordcounts = append(greekwordcounts, wordcount)
-						}
+					if err != nil {
+						fmt.Println("Skipping", path.Base(file)+":", err)
+						continue
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:p[@n='$2']/tei:cit[@n='$3']":
-				tempscheme = "B"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI3cit
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number}
-							identifier := strings.Join(id, ".")
-							identifier = strings.Join([]string{urn, identifier}, ":")
-							text := data.Node[i].Node[j].Node[k].InnerXML
-							unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-							text = stringcleaning(text)
-
-							words := greekWordRegExp.FindAllString(text, -1)
-							latinword := latinWordRegExp.FindAllString(text, -1)
-							arabicword := arabicWordRegExp.FindAllString(text, -1)
-							greekwords = greekwords + len(words)
-							wordcount := strconv.Itoa(len(words))
-							latinwords = latinwords + len(latinword)
-							arabicwords = arabicwords + len(arabicword)
-							latinwordcount := strconv.Itoa(len(latinword))
-							arabicwordcount := strconv.Itoa(len(arabicword))
-							latinwordcounts = append(latinwordcounts, latinwordcount)
-							arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-							identifiers = append(identifiers, identifier)
-							texts = append(texts, text)
-							greekwordcounts = append(greekwordcounts, wordcount)
-						}
-					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div[@n=\\'$1\\']" || querystring == "/tei:TEI.2/tei:text/tei:body/tei:div[@n=\\'$1\\']":
-				tempscheme = "C"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI1Direct
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for _, node := range data.Node {
-					identifier := strings.Join([]string{urn, node.Number}, ":")
-					text := node.InnerXML
-					unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-					text = stringcleaning(text)
-
-					words := greekWordRegExp.FindAllString(text, -1)
-					latinword := latinWordRegExp.FindAllString(text, -1)
-					arabicword := arabicWordRegExp.FindAllString(text, -1)
-					greekwords = greekwords + len(words)
-					wordcount := strconv.Itoa(len(words))
-					latinwords = latinwords + len(latinword)
-					arabicwords = arabicwords + len(arabicword)
-					latinwordcount := strconv.Itoa(len(latinword))
-					arabicwordcount := strconv.Itoa(len(arabicword))
-					latinwordcounts = append(latinwordcounts, latinwordcount)
-					arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-					identifiers = append(identifiers, identifier)
-					texts = append(texts, text)
-					greekwordcounts = append(greekwordcounts, wordcount)
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div/tei:div[@n='$1']":
-				tempscheme = "D"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI1Late
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for _, node := range data.Node {
-					identifier := strings.Join([]string{urn, node.Number}, ":")
-					text := node.InnerXML
-					unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-					text = stringcleaning(text)
-
-					words := greekWordRegExp.FindAllString(text, -1)
-					latinword := latinWordRegExp.FindAllString(text, -1)
-					arabicword := arabicWordRegExp.FindAllString(text, -1)
-					greekwords = greekwords + len(words)
-					wordcount := strconv.Itoa(len(words))
-					latinwords = latinwords + len(latinword)
-					arabicwords = arabicwords + len(arabicword)
-					latinwordcount := strconv.Itoa(len(latinword))
-					arabicwordcount := strconv.Itoa(len(arabicword))
-					latinwordcounts = append(latinwordcounts, latinwordcount)
-					arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-					identifiers = append(identifiers, identifier)
-					texts = append(texts, text)
-					greekwordcounts = append(greekwordcounts, wordcount)
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:p/tei:seg[@n='$1']":
-				tempscheme = "E"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI1pseg
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for _, node := range data.Node {
-					identifier := strings.Join([]string{urn, node.Number}, ":")
-					text := node.InnerXML
-					unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-					text = stringcleaning(text)
-
-					words := greekWordRegExp.FindAllString(text, -1)
-					latinword := latinWordRegExp.FindAllString(text, -1)
-					arabicword := arabicWordRegExp.FindAllString(text, -1)
-					greekwords = greekwords + len(words)
-					wordcount := strconv.Itoa(len(words))
-					latinwords = latinwords + len(latinword)
-					arabicwords = arabicwords + len(arabicword)
-					latinwordcount := strconv.Itoa(len(latinword))
-					arabicwordcount := strconv.Itoa(len(arabicword))
-					latinwordcounts = append(latinwordcounts, latinwordcount)
-					arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-					identifiers = append(identifiers, identifier)
-					texts = append(texts, text)
-					greekwordcounts = append(greekwordcounts, wordcount)
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:p[@n='$1']":
-				tempscheme = "F"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI1p
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for _, node := range data.Node {
-					identifier := strings.Join([]string{urn, node.Number}, ":")
-					text := node.InnerXML
-					unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-					text = stringcleaning(text)
-
-					words := greekWordRegExp.FindAllString(text, -1)
-					latinword := latinWordRegExp.FindAllString(text, -1)
-					arabicword := arabicWordRegExp.FindAllString(text, -1)
-					greekwords = greekwords + len(words)
-					wordcount := strconv.Itoa(len(words))
-					latinwords = latinwords + len(latinword)
-					arabicwords = arabicwords + len(arabicword)
-					latinwordcount := strconv.Itoa(len(latinword))
-					arabicwordcount := strconv.Itoa(len(arabicword))
-					latinwordcounts = append(latinwordcounts, latinwordcount)
-					arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-					identifiers = append(identifiers, identifier)
-					texts = append(texts, text)
-					greekwordcounts = append(greekwordcounts, wordcount)
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@n='$1']" || querystring == "/tei:TEI/tei:text/tei:body/div[@type='edition']/div[@n='$1']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n=\\'$1\\']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']":
-				tempscheme = "G"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI1
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for _, node := range data.Node {
-					identifier := strings.Join([]string{urn, node.Number}, ":")
-					text := node.InnerXML
-					unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-					text = stringcleaning(text)
-
-					words := greekWordRegExp.FindAllString(text, -1)
-					latinword := latinWordRegExp.FindAllString(text, -1)
-					arabicword := arabicWordRegExp.FindAllString(text, -1)
-					greekwords = greekwords + len(words)
-					wordcount := strconv.Itoa(len(words))
-					latinwords = latinwords + len(latinword)
-					arabicwords = arabicwords + len(arabicword)
-					latinwordcount := strconv.Itoa(len(latinword))
-					arabicwordcount := strconv.Itoa(len(arabicword))
-					latinwordcounts = append(latinwordcounts, latinwordcount)
-					arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-					identifiers = append(identifiers, identifier)
-					texts = append(texts, text)
-					greekwordcounts = append(greekwordcounts, wordcount)
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:p[@n='$2']":
-				tempscheme = "H"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI2p
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						id := []string{data.Node[i].Number, data.Node[i].Node[j].Number}
-						identifier := strings.Join(id, ".")
-						identifier = strings.Join([]string{urn, identifier}, ":")
-						text := data.Node[i].Node[j].InnerXML
-						unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-						text = stringcleaning(text)
-
-						words := greekWordRegExp.FindAllString(text, -1)
-						latinword := latinWordRegExp.FindAllString(text, -1)
-						arabicword := arabicWordRegExp.FindAllString(text, -1)
-						greekwords = greekwords + len(words)
-						wordcount := strconv.Itoa(len(words))
-						latinwords = latinwords + len(latinword)
-						arabicwords = arabicwords + len(arabicword)
-						latinwordcount := strconv.Itoa(len(latinword))
-						arabicwordcount := strconv.Itoa(len(arabicword))
-						latinwordcounts = append(latinwordcounts, latinwordcount)
-						arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-						identifiers = append(identifiers, identifier)
-						texts = append(texts, text)
-						greekwordcounts = append(greekwordcounts, wordcount)
+				default:
+					var levels []CitationLevel
+					levels, unknown = parseCitationLevels(headerinfo.RefPattern)
+					for i := range levels {
+						whatkind = append(whatkind, levels[i].Label)
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:ab[@n='$2']":
-				tempscheme = "I"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI2ab
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						id := []string{data.Node[i].Number, data.Node[i].Node[j].Number}
-						identifier := strings.Join(id, ".")
-						identifier = strings.Join([]string{urn, identifier}, ":")
-						text := data.Node[i].Node[j].InnerXML
-						unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-						text = stringcleaning(text)
-
-						words := greekWordRegExp.FindAllString(text, -1)
-						latinword := latinWordRegExp.FindAllString(text, -1)
-						arabicword := arabicWordRegExp.FindAllString(text, -1)
-						greekwords = greekwords + len(words)
-						wordcount := strconv.Itoa(len(words))
-						latinwords = latinwords + len(latinword)
-						arabicwords = arabicwords + len(arabicword)
-						latinwordcount := strconv.Itoa(len(latinword))
-						arabicwordcount := strconv.Itoa(len(arabicword))
-						latinwordcounts = append(latinwordcounts, latinwordcount)
-						arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-						identifiers = append(identifiers, identifier)
-						texts = append(texts, text)
-						greekwordcounts = append(greekwordcounts, wordcount)
+					if len(levels) > 0 {
+						var skipped []SkippedNode
+						passages, skipped = resolveCitationHierarchy(levels, root, version)
+						for _, v := range skipped {
+							fmt.Printf("Skipping <%s> with an empty @%s in %s.\n", v.Node.Name, v.Attr, path.Base(file))
+						}
+						schemename = levels[len(levels)-1].Pattern.XPath
 					}
 				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:lg/tei:l[@n='$2']":
-				tempscheme = "J"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI2lgl
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						id := []string{data.Node[i].Number, data.Node[i].Node[j].Number}
-						identifier := strings.Join(id, ".")
-						identifier = strings.Join([]string{urn, identifier}, ":")
-						text := data.Node[i].Node[j].InnerXML
-						unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-						text = stringcleaning(text)
-
-						words := greekWordRegExp.FindAllString(text, -1)
-						latinword := latinWordRegExp.FindAllString(text, -1)
-						arabicword := arabicWordRegExp.FindAllString(text, -1)
-						greekwords = greekwords + len(words)
-						wordcount := strconv.Itoa(len(words))
-						latinwords = latinwords + len(latinword)
-						arabicwords = arabicwords + len(arabicword)
-						latinwordcount := strconv.Itoa(len(latinword))
-						arabicwordcount := strconv.Itoa(len(arabicword))
-						latinwordcounts = append(latinwordcounts, latinwordcount)
-						arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-						identifiers = append(identifiers, identifier)
-						texts = append(texts, text)
-						greekwordcounts = append(greekwordcounts, wordcount)
+				if options.Milestone != "" {
+					passages, err = splitPassagesAtMilestones(passages, byteValue, options.Milestone)
+					if err != nil {
+						fmt.Println("Skipping", path.Base(file)+":", err)
+						continue
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div[@type='translation']/tei:div[@n='$1']/tei:div[@n='$2']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n=\\'$1\\']/tei:div[@n=\\'$2\\']" || querystring == "/tei:TEI/tei:text/tei:body/div[@type='edition']/div[@n='$1']/div[@n='$2']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@n='$1']/tei:div[@n='$2']":
-				tempscheme = "K"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI2
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						id := []string{data.Node[i].Number, data.Node[i].Node[j].Number}
-						identifier := strings.Join(id, ".")
-						identifier = strings.Join([]string{urn, identifier}, ":")
-						text := data.Node[i].Node[j].InnerXML
-						unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-						text = stringcleaning(text)
-
-						words := greekWordRegExp.FindAllString(text, -1)
-						latinword := latinWordRegExp.FindAllString(text, -1)
-						arabicword := arabicWordRegExp.FindAllString(text, -1)
-						greekwords = greekwords + len(words)
-						wordcount := strconv.Itoa(len(words))
-						latinwords = latinwords + len(latinword)
-						arabicwords = arabicwords + len(arabicword)
-						latinwordcount := strconv.Itoa(len(latinword))
-						arabicwordcount := strconv.Itoa(len(arabicword))
-						latinwordcounts = append(latinwordcounts, latinwordcount)
-						arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-						identifiers = append(identifiers, identifier)
-						texts = append(texts, text)
-						greekwordcounts = append(greekwordcounts, wordcount)
+					whatkind = append(whatkind, options.Milestone)
+					schemename = schemename + " split at " + options.Milestone
+				}
+				codes, languages := headerLanguages(headerinfo.Languages)
+				language := ""
+				if version != nil {
+					language = nodeLanguage(version)
+				} else if divs := versionDivs(root); len(divs) > 0 {
+					language = nodeLanguage(divs[0])
+				} else if len(passages) > 0 {
+					language = nodeLanguage(passages[0].Node)
+				}
+				language = codes.code(language)
+				if language == "" && len(languages) > 0 {
+					language = languages[0]
+					if len(languages) > 1 {
+						fmt.Println("Warning:", path.Base(file), "sets no language on its text and declares", strings.Join(languages, ", "), "in its header, using", language)
 					}
 				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@n='$1']/tei:div[@n='$2']/tei:div[@n='$3']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']/tei:div[@n='$3']" || querystring == "/tei:TEI/tei:text/tei:body/div[@type='edition']/div[@n='$1']/div[@n='$2']/div[@n='$3']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n=\\'$1\\']/tei:div[@n=\\'$2\\']/tei:div[@n=\\'$3\\']":
-				tempscheme = "L"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI3
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number}
-							identifier := strings.Join(id, ".")
-							identifier = strings.Join([]string{urn, identifier}, ":")
-							text := data.Node[i].Node[j].Node[k].InnerXML
-							unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-							text = stringcleaning(text)
-
-							words := greekWordRegExp.FindAllString(text, -1)
-							latinword := latinWordRegExp.FindAllString(text, -1)
-							arabicword := arabicWordRegExp.FindAllString(text, -1)
-							greekwords = greekwords + len(words)
-							wordcount := strconv.Itoa(len(words))
-							latinwords = latinwords + len(latinword)
-							arabicwords = arabicwords + len(arabicword)
-							latinwordcount := strconv.Itoa(len(latinword))
-							arabicwordcount := strconv.Itoa(len(arabicword))
-							latinwordcounts = append(latinwordcounts, latinwordcount)
-							arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-							identifiers = append(identifiers, identifier)
-							texts = append(texts, text)
-							greekwordcounts = append(greekwordcounts, wordcount)
+				kind := strings.Join(whatkind, ",")
+				var urn, versiontype string
+				if version != nil {
+					urn, versiontype = strings.TrimSpace(version.Attrs["n"]), version.Attrs["type"]
+				} else {
+					urn, versiontype = versionURN(file, root, byteValue, capitains)
+				}
+				versionurn, err := ctsurn.Parse(urn)
+				if err != nil {
+					fmt.Println("Skipping", path.Base(file)+":", err)
+					continue
+				}
+				// the catalog, the metadata and the passages all use the same form of the URN, e.g. without a trailing colon
+				urn = versionurn.String()
+				ctscatalog.URN = append(ctscatalog.URN, urn)
+				ctscatalog.CitationScheme = append(ctscatalog.CitationScheme, kind)
+				ctsmeta := capitains.lookup(file, urn)
+				group := strings.Join(headerinfo.Author, ",")
+				group = strings.Replace(group, "\n", " ", -1)
+				group = strings.TrimSpace(group)
+				ctscatalog.GroupName = append(ctscatalog.GroupName, firstNonEmpty(ctsmeta.GroupName, group))
+				worktitle := strings.Join(headerinfo.Title, ",")
+				worktitle = strings.Replace(worktitle, "\n", " ", -1)
+				worktitle = strings.TrimSpace(worktitle)
+				ctscatalog.WorkTitle = append(ctscatalog.WorkTitle, firstNonEmpty(ctsmeta.WorkTitle, worktitle))
+				ctscatalog.VersionLabel = append(ctscatalog.VersionLabel, ctsmeta.VersionLabel)
+				ctscatalog.ExemplarLabel = append(ctscatalog.ExemplarLabel, ctsmeta.ExemplarLabel)
+				ctscatalog.Description = append(ctscatalog.Description, ctsmeta.Description)
+				ctscatalog.VersionType = append(ctscatalog.VersionType, firstNonEmpty(ctsmeta.VersionType, versiontype))
+				ctscatalog.Online = append(ctscatalog.Online, "True")
+				ctscatalog.Language = append(ctscatalog.Language, language)
+				// adding contributors
+				contribution := JSONContr{}
+				for _, v := range headerinfo.Contributors {
+					tempContr := JSONContrs{}
+					tempContr.Resp = v.Resp
+					tempContr.PersName = v.PersName
+					contribution.Contribution = append(contribution.Contribution, tempContr)
+				}
+				ctscatalog.Contributors = append(ctscatalog.Contributors, contribution)
+				if len(unknown) > 0 || schemename == "" {
+					querystrings = append(querystrings, unknown...)
+				} else {
+					fmt.Print(len(whatkind))
+					scheme[schemename] = scheme[schemename] + 1
+					read = true
+					var invalid []string
+					for _, cited := range passages {
+						identifier, err := passageURN(versionurn, cited.Reference)
+						if err != nil {
+							invalid = append(invalid, strings.Join(cited.Reference, "."))
+							continue
 						}
-					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:l[@n='$1']":
-				tempscheme = "M"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI1Poem
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for _, node := range data.Node {
-					identifier := strings.Join([]string{urn, node.Number}, ":")
-					text := node.InnerXML
-					unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-					text = stringcleaning(text)
-
-					words := greekWordRegExp.FindAllString(text, -1)
-					latinword := latinWordRegExp.FindAllString(text, -1)
-					arabicword := arabicWordRegExp.FindAllString(text, -1)
-					greekwords = greekwords + len(words)
-					wordcount := strconv.Itoa(len(words))
-					latinwords = latinwords + len(latinword)
-					arabicwords = arabicwords + len(arabicword)
-					latinwordcount := strconv.Itoa(len(latinword))
-					arabicwordcount := strconv.Itoa(len(arabicword))
-					latinwordcounts = append(latinwordcounts, latinwordcount)
-					arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-					identifiers = append(identifiers, identifier)
-					texts = append(texts, text)
-					greekwordcounts = append(greekwordcounts, wordcount)
-				}
-			case querystring == "/tei:TEI.2/tei:text/tei:body/tei:div[@n=\\'$1\\']/tei:div[@n=\\'$2\\']" || querystring == "/tei:TEI/tei:text/tei:body/tei:div[@n=\\'$1\\']/tei:div[@n=\\'$2\\']":
-				tempscheme = "N"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI2direct
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						id := []string{data.Node[i].Number, data.Node[i].Node[j].Number}
-						identifier := strings.Join(id, ".")
-						identifier = strings.Join([]string{urn, identifier}, ":")
-						text := data.Node[i].Node[j].InnerXML
-						unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-						text = stringcleaning(text)
-
-						words := greekWordRegExp.FindAllString(text, -1)
-						latinword := latinWordRegExp.FindAllString(text, -1)
-						arabicword := arabicWordRegExp.FindAllString(text, -1)
-						greekwords = greekwords + len(words)
-						wordcount := strconv.Itoa(len(words))
-						latinwords = latinwords + len(latinword)
-						arabicwords = arabicwords + len(arabicword)
-						latinwordcount := strconv.Itoa(len(latinword))
-						arabicwordcount := strconv.Itoa(len(arabicword))
-						latinwordcounts = append(latinwordcounts, latinwordcount)
-						arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-						identifiers = append(identifiers, identifier)
-						texts = append(texts, text)
-						greekwordcounts = append(greekwordcounts, wordcount)
-					}
-				}
-			case querystring == "/tei:TEI.2/tei:text/tei:body/tei:div1[@n=\\'$1\\']/tei:div2[@n=\\'$2\\']/tei:div3[@n=\\'$3\\']":
-				tempscheme = "O"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI3DirectNumbered
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number}
-							identifier := strings.Join(id, ".")
-							identifier = strings.Join([]string{urn, identifier}, ":")
-							text := data.Node[i].Node[j].Node[k].InnerXML
-							unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-							text = stringcleaning(text)
-
-							words := greekWordRegExp.FindAllString(text, -1)
-							latinword := latinWordRegExp.FindAllString(text, -1)
-							arabicword := arabicWordRegExp.FindAllString(text, -1)
-							greekwords = greekwords + len(words)
-							wordcount := strconv.Itoa(len(words))
-							latinwords = latinwords + len(latinword)
-							arabicwords = arabicwords + len(arabicword)
-							latinwordcount := strconv.Itoa(len(latinword))
-							arabicwordcount := strconv.Itoa(len(arabicword))
-							latinwordcounts = append(latinwordcounts, latinwordcount)
-							arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-							identifiers = append(identifiers, identifier)
-							texts = append(texts, text)
-							greekwordcounts = append(greekwordcounts, wordcount)
+						if len(cited.Reference) > 0 {
+							citationtree.add(versionurn, cited.Reference, cited.Label, cited.Leaf)
 						}
-					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']/tei:l[@n='$3']":
-				tempscheme = "P"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI3poem
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number}
-							identifier := strings.Join(id, ".")
-							identifier = strings.Join([]string{urn, identifier}, ":")
-							text := data.Node[i].Node[j].Node[k].InnerXML
-							unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-							text = stringcleaning(text)
-
-							words := greekWordRegExp.FindAllString(text, -1)
-							latinword := latinWordRegExp.FindAllString(text, -1)
-							arabicword := arabicWordRegExp.FindAllString(text, -1)
-							greekwords = greekwords + len(words)
-							wordcount := strconv.Itoa(len(words))
-							latinwords = latinwords + len(latinword)
-							arabicwords = arabicwords + len(arabicword)
-							latinwordcount := strconv.Itoa(len(latinword))
-							arabicwordcount := strconv.Itoa(len(arabicword))
-							latinwordcounts = append(latinwordcounts, latinwordcount)
-							arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-							identifiers = append(identifiers, identifier)
-							texts = append(texts, text)
-							greekwordcounts = append(greekwordcounts, wordcount)
+						if !cited.Leaf {
+							continue
 						}
-					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:l[@n='$2']":
-				tempscheme = "Q"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI2Poem
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						id := []string{data.Node[i].Number, data.Node[i].Node[j].Number}
-						identifier := strings.Join(id, ".")
-						identifier = strings.Join([]string{urn, identifier}, ":")
-						text := data.Node[i].Node[j].InnerXML
+						fragment := parseFragment(cited.innerXML(byteValue))
+						notes.collect(identifier, fragment, breaks)
+						apparatus = collectApparatus(apparatus, identifier, fragment, breaks)
+						text := applyElementPolicy(fragment, policy)
+						entities.collect(identifier, fragment, policy, breaks)
+						speaker := speakerOf(cited.Node, byteValue, breaks)
+						if speaker != "" {
+							speakers.add(urn, identifier, speaker)
+						}
+						speakernames = append(speakernames, speaker)
+						passagelanguages = append(passagelanguages, passageLanguages(cited, fragment, language, codes))
 						unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-						text = stringcleaning(text)
-						words := greekWordRegExp.FindAllString(text, -1)
-						latinword := latinWordRegExp.FindAllString(text, -1)
-						arabicword := arabicWordRegExp.FindAllString(text, -1)
-						greekwords = greekwords + len(words)
-						wordcount := strconv.Itoa(len(words))
-						latinwords = latinwords + len(latinword)
-						arabicwords = arabicwords + len(arabicword)
-						latinwordcount := strconv.Itoa(len(latinword))
-						arabicwordcount := strconv.Itoa(len(arabicword))
-						latinwordcounts = append(latinwordcounts, latinwordcount)
-						arabicwordcounts = append(arabicwordcounts, arabicwordcount)
+						text = extractText(text, breaks)
+						wordcounts.add(text)
 						identifiers = append(identifiers, identifier)
 						texts = append(texts, text)
-						greekwordcounts = append(greekwordcounts, wordcount)
 					}
-				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']/tei:div[@n='$3']/tei:div[@n='$4']":
-				tempscheme = "R"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
-				filecount = filecount + 1
-				var data StartTEI4div
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							for l := range data.Node[i].Node[j].Node[k].Node {
-								id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number, data.Node[i].Node[j].Node[k].Node[l].Number}
-								identifier := strings.Join(id, ".")
-								identifier = strings.Join([]string{urn, identifier}, ":")
-								text := data.Node[i].Node[j].Node[k].Node[l].InnerXML
-								unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-								text = stringcleaning(text)
-
-								words := greekWordRegExp.FindAllString(text, -1)
-								latinword := latinWordRegExp.FindAllString(text, -1)
-								arabicword := arabicWordRegExp.FindAllString(text, -1)
-								greekwords = greekwords + len(words)
-								wordcount := strconv.Itoa(len(words))
-								latinwords = latinwords + len(latinword)
-								arabicwords = arabicwords + len(arabicword)
-								latinwordcount := strconv.Itoa(len(latinword))
-								arabicwordcount := strconv.Itoa(len(arabicword))
-								latinwordcounts = append(latinwordcounts, latinwordcount)
-								arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-								identifiers = append(identifiers, identifier)
-								texts = append(texts, text)
-								greekwordcounts = append(greekwordcounts, wordcount)
-							}
-						}
+					if len(invalid) > 0 {
+						fmt.Println("Warning: skipping", len(invalid), "passages of", urn, "in", path.Base(file), "whose references make no valid CTS URN:", strings.Join(invalid, ", "))
 					}
 				}
-			case querystring == "/tei:TEI/tei:text/tei:body/tei:div/tei:div[@n='$1']/tei:div[@n='$2']/tei:cit[@n='$3']":
-				tempscheme = "S"
-				fmt.Print(tempscheme)
-				scheme[tempscheme] = scheme[tempscheme] + 1
+			}
+			if read {
 				filecount = filecount + 1
-				var data StartTEI3divcit
-				err = xml.Unmarshal(byteValue, &data)
-				check(err)
-				for i := range data.Node {
-					for j := range data.Node[i].Node {
-						for k := range data.Node[i].Node[j].Node {
-							id := []string{data.Node[i].Number, data.Node[i].Node[j].Number, data.Node[i].Node[j].Node[k].Number}
-							identifier := strings.Join(id, ".")
-							identifier = strings.Join([]string{urn, identifier}, ":")
-							text := data.Node[i].Node[j].Node[k].InnerXML
-							unstrippedTexts = append(unstrippedTexts, strings.TrimSpace(text))
-							text = stringcleaning(text)
-
-							words := greekWordRegExp.FindAllString(text, -1)
-							latinword := latinWordRegExp.FindAllString(text, -1)
-							arabicword := arabicWordRegExp.FindAllString(text, -1)
-							greekwords = greekwords + len(words)
-							wordcount := strconv.Itoa(len(words))
-							latinwords = latinwords + len(latinword)
-							arabicwords = arabicwords + len(arabicword)
-							latinwordcount := strconv.Itoa(len(latinword))
-							arabicwordcount := strconv.Itoa(len(arabicword))
-							latinwordcounts = append(latinwordcounts, latinwordcount)
-							arabicwordcounts = append(arabicwordcounts, arabicwordcount)
-							identifiers = append(identifiers, identifier)
-							texts = append(texts, text)
-							greekwordcounts = append(greekwordcounts, wordcount)
-						}
-					}
-				}
-			default:
-				querystrings = append(querystrings, querystring)
 			}
 		}
 		xmlFile.Close()
 	}
+	ctscatalog = options.normalizeCatalog(ctscatalog)
 	result := removeDuplicatesUnordered(querystrings)
 	if len(result) != 0 {
 		fmt.Println("Not read:", len(result))
 		fmt.Println("Those XPATH are unknown:", result)
 	}
 	fmt.Println()
-	fmt.Println("Read", filecount, "of", len(xmlFiles), "files.")
+	if options.Input == "" {
+		fmt.Println("Read", filecount, "of", len(xmlFiles), "files.")
+	}
 	if len(noxpath) != 0 {
 		fmt.Println(len(noxpath), " files have no XPATH!")
 		fmt.Println("See those: ", noxpath)
 	}
 	fmt.Println("Write nodes to file now:")
 
-	switch len(os.Args) {
-	case 2:
+	switch mode {
+	case "":
 		fmt.Println("Writing CEX-File")
-		writeCEX(outputFile, ctscatalog, identifiers, texts)
-	case 3:
-		if os.Args[2] == "-CSV" {
+		collections := library.Collections
+		relations := library.Relations
+		if len(notes.URN) > 0 {
+			collections = append(collections, notes.citeCollection())
+			notes.relations(&relations)
+		}
+		if len(apparatus) > 0 {
+			collections = append(collections, apparatusCollection(apparatus))
+		}
+		if len(entities.URN) > 0 {
+			collections = append(collections, entities.citeCollection())
+			entities.relations(&relations)
+		}
+		if len(speakers.URN) > 0 {
+			collections = append(collections, speakers.citeCollection())
+			speakers.relations(&relations)
+		}
+		translationRelations(ctscatalog, identifiers, &relations)
+		datamodels := dataModels(library.DataModels, collections)
+		if options.Tokens {
+			ctscatalog, identifiers, texts = addTokenExemplars(ctscatalog, identifiers, texts, tokenizer.Options{LatinEnclitics: options.Enclitics, NeVe: options.NeVe})
+		}
+		citelibrary := options.citeLibrary(library)
+		if options.Split != "" {
+			writeSplitCEX(outputFile, options, citelibrary, ctscatalog, identifiers, texts, collections, relations, datamodels)
+			break
+		}
+		delimiter := options.delimiter(cexValues(citelibrary, ctscatalog, identifiers, texts, collections, relations, datamodels)...)
+		writeCEX(outputFile, delimiter, citelibrary, ctscatalog, identifiers, texts, collections, relations, datamodels)
+	default:
+		if mode == "-CSV" {
 			fmt.Println("Writing CSV-File")
-			writeCSV(outputFile, identifiers, texts, greekwordcounts, latinwordcounts, arabicwordcounts)
+			var searchtexts []string
+			if options.Search {
+				for _, v := range texts {
+					searchtexts = append(searchtexts, searchText(v))
+				}
+			}
+			writeCSV(outputFile, options.delimiter(identifiers, texts, speakernames, passagelanguages, searchtexts), identifiers, texts, wordcounts, speakernames, passagelanguages, searchtexts)
+			if len(notes.URN) > 0 {
+				fmt.Println("Writing Notes CSV-File")
+				collection := notes.citeCollection()
+				writeCiteCSV(sideFile(outputFile, "notes", ".csv"), options.delimiter(collection.values()...), collection)
+			}
+			if len(entities.URN) > 0 {
+				fmt.Println("Writing Entities CSV-File")
+				collection := entities.citeCollection()
+				writeCiteCSV(sideFile(outputFile, "entities", ".csv"), options.delimiter(collection.values()...), collection)
+			}
 		}
-		if os.Args[2] == "-JSON" {
+		if mode == "-JSON" {
 			fmt.Println("Writing JSON-File")
 			writeJSON(outputFile, ctscatalog)
+			if len(apparatus) > 0 {
+				fmt.Println("Writing Apparatus JSON-File")
+				writeApparatusJSON(sideFile(outputFile, "apparatus", ".json"), apparatus)
+			}
+			if len(entities.URN) > 0 {
+				fmt.Println("Writing Entities JSON-File")
+				writeEntitiesJSON(sideFile(outputFile, "entities", ".json"), entities)
+			}
 		}
-		if os.Args[2] == "-XML" {
+		if mode == "-XML" {
 			fmt.Println("Writing XML-File")
 			writeXML(outputFile, ctscatalog)
 		}
-		if os.Args[2] == "-SQL" {
+		if mode == "-SQL" {
 			fmt.Println("Writing SQLite DB")
-			writeSQL(outputFile, ctscatalog)
+			writeSQL(outputFile, ctscatalog, identifiers, texts)
 		}
-		if os.Args[2] == "-HTML" {
+		if mode == "-HTML" {
 			fmt.Println("Writing HTML Report")
-			writeHTML(outputFile, ctscatalog, identifiers, texts, greekwordcounts, latinwordcounts, arabicwordcounts, greekwords, latinwords, arabicwords)
+			writeHTML(outputFile, ctscatalog, identifiers, texts, wordcounts)
 		}
-		if os.Args[2] == "-Markdown" {
+		if mode == "-Markdown" {
 			fmt.Println("Writing Markdown Files")
-			writeMarkdown(ctscatalog, identifiers, unstrippedTexts)
+			writeMarkdown(ctscatalog, citationtree, identifiers, unstrippedTexts)
+		}
+		if mode == "-Tree" {
+			fmt.Println("Writing Citation Tree")
+			writeTree(outputFile, citationtree)
 		}
-		if os.Args[2] == "-Cat" {
+		if mode == "-Cat" {
 			fmt.Println("Writing JSON Catalog")
 			var jsoncat = []JSONCatalog{}
 			for i := range ctscatalog.URN {
 				scaifestring := ""
-				itemwords := 0
-				found := false
-				for j, v := range identifiers {
-					if !found {
-						if strings.Contains(v, ctscatalog.URN[i]) {
-							scaifestring = "https://scaife.perseus.org/reader/" + v
-							found = true
-						}
-					}
-					if strings.Contains(v, ctscatalog.URN[i]) {
-						greek, _ := strconv.Atoi(greekwordcounts[j])
-						latin, _ := strconv.Atoi(latinwordcounts[j])
-						arabic, _ := strconv.Atoi(arabicwordcounts[j])
-						itemwords = itemwords + greek + latin + arabic
+				for _, v := range identifiers {
+					if passageOf(v, ctscatalog.URN[i]) {
+						scaifestring = "https://scaife.perseus.org/reader/" + v
+						break
 					}
 				}
+				workcounts := wordcounts.work(identifiers, ctscatalog.URN[i])
 				catitem := JSONCatalog{
 					URN:       ctscatalog.URN[i],
 					GroupName: ctscatalog.GroupName[i],
 					WorkName:  ctscatalog.WorkTitle[i],
 					Language:  ctscatalog.Language[i],
-					WordCount: itemwords,
+					WordCount: sum(workcounts),
+					Scripts:   wordcounts.byScript(workcounts),
 					Scaife:    scaifestring,
 				}
 				jsoncat = append(jsoncat, catitem)
 			}
+			totals := wordcounts.byScript(wordcounts.Total)
 			var report = ReportJSON{Nodecount: len(identifiers),
-				Greekwords:  greekwords,
-				Latinwords:  latinwords,
-				Arabicwords: arabicwords,
+				Greekwords:  totals["Greek"],
+				Latinwords:  totals["Latin"],
+				Arabicwords: totals["Arabic"],
+				Scripts:     totals,
 				Catalog:     jsoncat}
 			writeCatalog(outputFile, report)
 		}
-	default:
-		fmt.Println("Invalid number of arguments")
 	}
 
 	fmt.Println("Wrote", len(identifiers), "nodes.")
-	fmt.Println(greekwords, "words written in the Greek alphabet.")
-	fmt.Println(latinwords, "words written in the Latin alphabet.")
-	fmt.Println(arabicwords, "words written in the Arabic alphabet.")
+	for i, v := range wordcounts.Scripts {
+		fmt.Println(wordcounts.Total[i], "words written in the", v, "script.")
+	}
 	fmt.Println("The following schemes were used:")
 	for i, v := range scheme {
 		fmt.Println(i, v)
 	}
 }
 
-func writeMarkdown(ctscatalog CTSCatalog, identifier, texts []string) {
+func writeMarkdown(ctscatalog CTSCatalog, citationtree CitationTree, identifier, texts []string) {
 	fmt.Println()
 	if _, err := os.Stat("TEITOCEX_OUTPUT"); os.IsNotExist(err) {
 		err := os.Mkdir("TEITOCEX_OUTPUT", 0700)
@@ -1641,8 +563,12 @@ func writeMarkdown(ctscatalog CTSCatalog, identifier, texts []string) {
 	}
 	for i, v := range ctscatalog.URN {
 		outputStrs := []string{}
-		ss := strings.Split(v, ":")
-		filen := ss[len(ss)-1]
+		versionurn, err := ctsurn.Parse(v)
+		if err != nil {
+			fmt.Println("Skipping Markdown for", err)
+			continue
+		}
+		filen := versionurn.WorkComponent()
 		filename := filepath.Join([]string{"TEITOCEX_OUTPUT", string(filen + ".md")}...)
 		outputStrs = append(outputStrs, "---\n")
 		outputStrs = append(outputStrs, "title: \"")
@@ -1694,124 +620,48 @@ func writeMarkdown(ctscatalog CTSCatalog, identifier, texts []string) {
 		outputStrs = append(outputStrs, "# ")
 		outputStrs = append(outputStrs, ctscatalog.WorkTitle[i])
 		outputStrs = append(outputStrs, "\n")
-		citationFormat := strings.Split(ctscatalog.CitationScheme[i], ",")
-		firstlevel := ""
-		secondlevel := ""
-		lineNumber := 0
-		hit := false
-		passage := false
+		passages := make(map[string]string)
 		for i2, v2 := range identifier {
-			if strings.Contains(v2, v) {
-				passage = true
-				lineNumber++
+			if strings.HasPrefix(v2, v+":") {
+				passages[v2] = texts[i2]
+			}
+		}
+		levels := len(strings.Split(ctscatalog.CitationScheme[i], ","))
+		lineNumber := 0
+		for j, node := range citationtree.URN {
+			if !strings.HasPrefix(node, v+":") {
+				continue
+			}
+			nodeurn, err := ctsurn.Parse(node)
+			if err != nil {
+				fmt.Println("Skipping Markdown node", err)
+				continue
+			}
+			citation := nodeurn.Passage()
+			label := strings.Title(strings.TrimSpace(citationtree.Level[j]))
+			heading := strings.Repeat("#", citationtree.Depth[j]+1)
+			if levels == 1 && strings.ToLower(label) != "book" {
+				heading = "###"
+			}
+			if !citationtree.Leaf[j] {
+				outputStrs = append(outputStrs, "\n"+heading+" "+label+" "+citation+"\n\n")
+				lineNumber = 0
+				continue
+			}
+			if strings.ToLower(label) != "line" {
+				outputStrs = append(outputStrs, "\n"+heading+" "+label+" "+citation+"\n\n")
+				outputStrs = append(outputStrs, passages[node])
 				outputStrs = append(outputStrs, "\n")
-				ss2 := strings.Split(v2, ":")
-				citation := ss2[len(ss2)-1]
-				switch len(citationFormat) {
-				case 2:
-					ss3 := strings.Split(citation, ".")
-					newFirst := ss3[0]
-					if firstlevel != newFirst {
-						firstlevel = newFirst
-						outputStrs = append(outputStrs, "## ")
-						outputStrs = append(outputStrs, strings.Title(citationFormat[0]))
-						outputStrs = append(outputStrs, " ")
-						outputStrs = append(outputStrs, firstlevel)
-						outputStrs = append(outputStrs, "\n\n")
-						hit = true
-					}
-					if strings.TrimSpace(strings.ToLower(citationFormat[1])) != "line" {
-						outputStrs = append(outputStrs, "### ")
-						outputStrs = append(outputStrs, citationFormat[1])
-						outputStrs = append(outputStrs, " ")
-						outputStrs = append(outputStrs, citation)
-						outputStrs = append(outputStrs, "\n\n")
-						outputStrs = append(outputStrs, texts[i2])
-						outputStrs = append(outputStrs, "\n")
-					} else {
-						if math.Mod(float64(lineNumber), 10) == 0 {
-							outputStrs = append(outputStrs, "\n#### ")
-							outputStrs = append(outputStrs, citation)
-							outputStrs = append(outputStrs, "\n\n")
-						}
-						if hit {
-							hit = false
-							lineNumber = 0
-						}
-						outputStrs = append(outputStrs, texts[i2])
-						outputStrs = append(outputStrs, "  \n")
-					}
-				case 3:
-					ss3 := strings.Split(citation, ".")
-					newFirst := ss3[0]
-					newSecond := ss3[1]
-					if firstlevel != newFirst {
-						firstlevel = newFirst
-						secondlevel = newSecond
-						outputStrs = append(outputStrs, "## ")
-						outputStrs = append(outputStrs, strings.Title(citationFormat[0]))
-						outputStrs = append(outputStrs, " ")
-						outputStrs = append(outputStrs, firstlevel)
-						outputStrs = append(outputStrs, "\n\n")
-						outputStrs = append(outputStrs, "### ")
-						outputStrs = append(outputStrs, strings.Title(citationFormat[1]))
-						outputStrs = append(outputStrs, " ")
-						outputStrs = append(outputStrs, firstlevel)
-						outputStrs = append(outputStrs, ".")
-						outputStrs = append(outputStrs, newSecond)
-						outputStrs = append(outputStrs, "\n\n")
-						hit = true
-					} else {
-						if secondlevel != newSecond {
-							outputStrs = append(outputStrs, "### ")
-							outputStrs = append(outputStrs, strings.Title(citationFormat[1]))
-							outputStrs = append(outputStrs, " ")
-							outputStrs = append(outputStrs, firstlevel)
-							outputStrs = append(outputStrs, ".")
-							outputStrs = append(outputStrs, newSecond)
-							outputStrs = append(outputStrs, "\n\n")
-							hit = true
-						}
-					}
-					if strings.TrimSpace(strings.ToLower(citationFormat[2])) != "line" {
-						outputStrs = append(outputStrs, "#### ")
-						outputStrs = append(outputStrs, strings.Title(citationFormat[2]))
-						outputStrs = append(outputStrs, " ")
-						outputStrs = append(outputStrs, citation)
-						outputStrs = append(outputStrs, "\n\n")
-						outputStrs = append(outputStrs, texts[i2])
-						outputStrs = append(outputStrs, "\n")
-					} else {
-						if math.Mod(float64(lineNumber), 10) == 0 {
-							outputStrs = append(outputStrs, "\n#### ")
-							outputStrs = append(outputStrs, citation)
-							outputStrs = append(outputStrs, "\n\n")
-						}
-						if hit {
-							hit = false
-							lineNumber = 0
-						}
-						outputStrs = append(outputStrs, texts[i2])
-						outputStrs = append(outputStrs, "  \n")
-					}
-				default:
-					if strings.TrimSpace(strings.ToLower(citationFormat[0])) == "book" {
-						outputStrs = append(outputStrs, "## ")
-					} else {
-						outputStrs = append(outputStrs, "### ")
-					}
-					outputStrs = append(outputStrs, strings.Title(citationFormat[0]))
-					outputStrs = append(outputStrs, " ")
-					outputStrs = append(outputStrs, citation)
-					outputStrs = append(outputStrs, "\n\n")
-					outputStrs = append(outputStrs, texts[i2])
-					outputStrs = append(outputStrs, "\n\n")
-				}
-			} else {
-				if passage {
-					break
-				}
+				continue
 			}
+			lineNumber++
+			if math.Mod(float64(lineNumber), 10) == 0 {
+				outputStrs = append(outputStrs, "\n#### ")
+				outputStrs = append(outputStrs, citation)
+				outputStrs = append(outputStrs, "\n\n")
+			}
+			outputStrs = append(outputStrs, passages[node])
+			outputStrs = append(outputStrs, "  \n")
 		}
 		f, err := os.Create(filename)
 		check(err)
@@ -1831,6 +681,16 @@ func writeCatalog(outputFile string, report ReportJSON) {
 	check(err)
 }
 
+func writeTree(outputFile string, citationtree CitationTree) {
+	jsontree, err1 := json.Marshal(citationtree)
+	check(err1)
+	f, err2 := os.Create(outputFile)
+	check(err2)
+	defer f.Close()
+	_, err := f.WriteString(string(jsontree))
+	check(err)
+}
+
 type fileConnection struct {
 	*os.File
 }
@@ -1840,7 +700,7 @@ func (f fileConnection) writeToFile(input string) {
 	check(err)
 }
 
-func writeHTML(outputFile string, ctscatalog CTSCatalog, identifiers, texts, greekwordcounts, latinwordcounts, arabicwordcounts []string, greekwords, latinwords, arabicwords int) {
+func writeHTML(outputFile string, ctscatalog CTSCatalog, identifiers, texts []string, wordcounts WordCounts) {
 	f, err := os.Create(outputFile)
 	check(err)
 	defer f.Close()
@@ -1848,15 +708,11 @@ func writeHTML(outputFile string, ctscatalog CTSCatalog, identifiers, texts, gre
 	// HTML Header
 	// HTML BODY
 	fconnection.writeToFile("<div>\n")
-	fconnection.writeToFile("<p>")
-	fconnection.writeToFile("Greek words:" + strconv.Itoa(greekwords))
-	fconnection.writeToFile("</p>\n")
-	fconnection.writeToFile("<p>")
-	fconnection.writeToFile("Latin words:" + strconv.Itoa(latinwords))
-	fconnection.writeToFile("</p>\n")
-	fconnection.writeToFile("<p>")
-	fconnection.writeToFile("Arabic words:" + strconv.Itoa(arabicwords))
-	fconnection.writeToFile("</p>\n")
+	for i, v := range wordcounts.Scripts {
+		fconnection.writeToFile("<p>")
+		fconnection.writeToFile(v + " words:" + strconv.Itoa(wordcounts.Total[i]))
+		fconnection.writeToFile("</p>\n")
+	}
 	fconnection.writeToFile("</div>\n")
 	fconnection.writeToFile("</hr>\n")
 	for i := range ctscatalog.URN {
@@ -1880,69 +736,72 @@ func writeHTML(outputFile string, ctscatalog CTSCatalog, identifiers, texts, gre
 		fconnection.writeToFile("ExemplarLabel:" + ctscatalog.ExemplarLabel[i])
 		fconnection.writeToFile("</p>\n")
 		fconnection.writeToFile("<p>")
+		fconnection.writeToFile("VersionType:" + ctscatalog.VersionType[i])
+		fconnection.writeToFile("</p>\n")
+		fconnection.writeToFile("<p>")
 		fconnection.writeToFile("Language:" + ctscatalog.Language[i])
 		fconnection.writeToFile("</p>\n")
-		found := false
-		wordcount := 0
-		for j, v := range identifiers {
-			if !found {
-				if strings.Contains(v, ctscatalog.URN[i]) {
-					fconnection.writeToFile("<p>")
-					fconnection.writeToFile("First URN:" + v)
-					fconnection.writeToFile("</p>\n")
-					fconnection.writeToFile("<p>")
-					fconnection.writeToFile("<a href=\"https://scaife.perseus.org/reader/" + v + "\">Read Online</a>")
-					fconnection.writeToFile("</p>\n")
-					found = true
-				}
-			}
-			if strings.Contains(v, ctscatalog.URN[i]) {
-				greek, _ := strconv.Atoi(greekwordcounts[j])
-				latin, _ := strconv.Atoi(latinwordcounts[j])
-				arabic, _ := strconv.Atoi(arabicwordcounts[j])
-				wordcount = wordcount + greek + latin + arabic
+		for _, v := range identifiers {
+			if passageOf(v, ctscatalog.URN[i]) {
+				fconnection.writeToFile("<p>")
+				fconnection.writeToFile("First URN:" + v)
+				fconnection.writeToFile("</p>\n")
+				fconnection.writeToFile("<p>")
+				fconnection.writeToFile("<a href=\"https://scaife.perseus.org/reader/" + v + "\">Read Online</a>")
+				fconnection.writeToFile("</p>\n")
+				break
 			}
 		}
+		workcounts := wordcounts.work(identifiers, ctscatalog.URN[i])
 		fconnection.writeToFile("<p>")
-		fconnection.writeToFile("Words:" + strconv.Itoa(wordcount))
+		fconnection.writeToFile("Words:" + strconv.Itoa(sum(workcounts)))
 		fconnection.writeToFile("</p>\n")
+		for j, v := range wordcounts.Scripts {
+			fconnection.writeToFile("<p>")
+			fconnection.writeToFile(v + " words:" + strconv.Itoa(workcounts[j]))
+			fconnection.writeToFile("</p>\n")
+		}
 		fconnection.writeToFile("</div>\n")
 		fconnection.writeToFile("</hr>\n")
 	}
 }
 
-func writeCEX(outputFile string, ctscatalog CTSCatalog, identifiers, texts []string) {
+func writeCEXVersion(fconnection fileConnection) {
+	fconnection.writeToFile("#!cexversion")
+	fconnection.writeToFile("\n\n")
+	fconnection.writeToFile("3.0")
+	fconnection.writeToFile("\n\n")
+}
+
+func writeCEX(outputFile string, delimiter string, citelibrary CiteLibrary, ctscatalog CTSCatalog, identifiers, texts []string, collections []CiteCollection, relations CiteRelations, datamodels []CiteDataModel) {
 	f, err := os.Create(outputFile)
 	check(err)
 	fconnection := fileConnection{f}
 	defer f.Close()
 
-	// cexversion
-	fconnection.writeToFile("#!cexversion")
-	fconnection.writeToFile("\n\n")
-	fconnection.writeToFile("3.0")
-	fconnection.writeToFile("\n\n")
+	writeCEXVersion(fconnection)
+	writeCiteLibrary(fconnection, delimiter, citelibrary)
 
 	// ctscatalog
 	fconnection.writeToFile("#!ctscatalog")
 	fconnection.writeToFile("\n\n")
-	fconnection.writeToFile("urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#language")
+	fconnection.writeToFile(strings.Join([]string{"urn", "citationScheme", "groupName", "workTitle", "versionLabel", "exemplarLabel", "online", "language"}, delimiter))
 	fconnection.writeToFile("\n")
 	for i := range ctscatalog.URN {
 		fconnection.writeToFile(ctscatalog.URN[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.CitationScheme[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.GroupName[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.WorkTitle[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.VersionLabel[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.ExemplarLabel[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.Online[i])
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(ctscatalog.Language[i])
 		fconnection.writeToFile("\n")
 	}
@@ -1953,13 +812,16 @@ func writeCEX(outputFile string, ctscatalog CTSCatalog, identifiers, texts []str
 	fconnection.writeToFile("\n\n")
 
 	for i := range identifiers {
-		newtext := strings.Replace(texts[i], "#", "", -1)
-		newtext = strings.Replace(newtext, `"`, `\"`, -1)
 		fconnection.writeToFile(identifiers[i])
-		fconnection.writeToFile("#")
-		fconnection.writeToFile(newtext)
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(texts[i])
 		fconnection.writeToFile("\n")
 	}
+	fconnection.writeToFile("\n")
+
+	writeCiteCollections(fconnection, delimiter, collections)
+	writeRelations(fconnection, delimiter, relations)
+	writeDataModels(fconnection, delimiter, datamodels)
 }
 
 func getRecord(ctscatalog CTSCatalog, i int) (record OAIDCRecord) {
@@ -1978,29 +840,47 @@ func getRecord(ctscatalog CTSCatalog, i int) (record OAIDCRecord) {
 	record.Publisher = "OGLP"
 	record.ViewURL = "http://cts.dh.uni-leipzig.de/text/urn:cts:greekLit:" + ctscatalog.URN[i]
 	record.Description[0] = "http://cts.dh.uni-leipzig.de/text/urn:cts:greekLit:" + ctscatalog.URN[i]
+	record.Description[1] = ctscatalog.Description[i]
 	return (record)
 }
 
-func writeSQL(outputFile string, ctscatalog CTSCatalog) {
+func writeSQL(outputFile string, ctscatalog CTSCatalog, identifiers, texts []string) {
 
 	var record OAIDCRecord
 	db, err := sql.Open("sqlite3", outputFile)
 	check(err)
-	records, _ := db.Prepare("INSERT INTO records(id, item_id, metadata_format_id, xml, state) values(? ,?, 1, ?, 1)")
-	items, _ := db.Prepare("INSERT INTO items(id, id_ext, state, timestamp) values(? ,?, 'active', '1970-01-01 00:00:00')")
-
-	for i := range ctscatalog.URN {
-		record = getRecord(ctscatalog, i)
-		if record.Creator != "" {
-			output, err := xml.MarshalIndent(record, "", " ")
-			check(err)
-			fmt.Print(".")
-			_, err = records.Exec(i, i, output)
-			check(err)
-			_, err = items.Exec(i, ctscatalog.URN[i])
-			check(err)
+	records, err1 := db.Prepare("INSERT INTO records(id, item_id, metadata_format_id, xml, state) values(? ,?, 1, ?, 1)")
+	items, err2 := db.Prepare("INSERT INTO items(id, id_ext, state, timestamp) values(? ,?, 'active', '1970-01-01 00:00:00')")
+	if err1 != nil || err2 != nil {
+		fmt.Println("No OAI-PMH tables found, writing passages only.")
+	} else {
+		for i := range ctscatalog.URN {
+			record = getRecord(ctscatalog, i)
+			if record.Creator != "" {
+				output, err := xml.MarshalIndent(record, "", " ")
+				check(err)
+				fmt.Print(".")
+				_, err = records.Exec(i, i, output)
+				check(err)
+				_, err = items.Exec(i, ctscatalog.URN[i])
+				check(err)
+			}
 		}
 	}
+	// passages, read back by the query command
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS passages(seq INTEGER PRIMARY KEY, urn TEXT NOT NULL, text TEXT NOT NULL)")
+	check(err)
+	_, err = db.Exec("DELETE FROM passages")
+	check(err)
+	tx, err := db.Begin()
+	check(err)
+	passages, err := tx.Prepare("INSERT INTO passages(seq, urn, text) values(?, ?, ?)")
+	check(err)
+	for i := range identifiers {
+		_, err = passages.Exec(i, identifiers[i], texts[i])
+		check(err)
+	}
+	check(tx.Commit())
 	db.Close()
 
 }
@@ -2048,36 +928,52 @@ func writeJSON(outputFile string, ctscatalog CTSCatalog) {
 	check(err)
 }
 
-func writeCSV(outputFile string, identifiers, texts, greekwordcounts, latinwordcounts, arabicwordcounts []string) {
+func writeCSV(outputFile string, delimiter string, identifiers, texts []string, wordcounts WordCounts, speakernames, passagelanguages, searchtexts []string) {
 	f, err := os.Create(outputFile)
 	check(err)
 	defer f.Close()
 	fconnection := fileConnection{f}
 
-	fconnection.writeToFile("identifier#text#GreekWords#LatinWords#ArabicWords#Workgroup#Work#WorkVerbose\n")
+	header := []string{"identifier", "text"}
+	for _, v := range wordcounts.Scripts {
+		header = append(header, v+"Words")
+	}
+	header = append(header, "Workgroup", "Work", "WorkVerbose", "Speaker", "Language")
+	if searchtexts != nil {
+		header = append(header, "SearchText")
+	}
+	fconnection.writeToFile(strings.Join(header, delimiter))
+	fconnection.writeToFile("\n")
 
 	for i := range identifiers {
-		newtext := strings.Replace(texts[i], "#", "", -1)
-		newtext = strings.Replace(newtext, `"`, `\"`, -1)
+		passageurn, err := ctsurn.Parse(identifiers[i])
+		if err != nil {
+			fmt.Println("Skipping CSV row", err)
+			continue
+		}
 		fconnection.writeToFile(identifiers[i])
-		fconnection.writeToFile("#")
-		fconnection.writeToFile(newtext)
-		fconnection.writeToFile("#")
-		fconnection.writeToFile(greekwordcounts[i])
-		fconnection.writeToFile("#")
-		fconnection.writeToFile(latinwordcounts[i])
-		fconnection.writeToFile("#")
-		fconnection.writeToFile(arabicwordcounts[i])
-		fconnection.writeToFile("#")
-		baseurn := strings.Split(identifiers[i], ":")[3]
-		urnslice := strings.Split(baseurn, ".")
-		workgroup := urnslice[0]
-		work := strings.Join(urnslice[1:], ".")
-		fconnection.writeToFile(workgroup)
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(texts[i])
+		fconnection.writeToFile(delimiter)
+		for _, v := range wordcounts.Passage[i] {
+			fconnection.writeToFile(strconv.Itoa(v))
+			fconnection.writeToFile(delimiter)
+		}
+		baseurn := passageurn.WorkComponent()
+		work := strings.TrimPrefix(strings.TrimPrefix(baseurn, passageurn.Textgroup), ".")
+		fconnection.writeToFile(passageurn.Textgroup)
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(work)
-		fconnection.writeToFile("#")
+		fconnection.writeToFile(delimiter)
 		fconnection.writeToFile(baseurn)
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(speakernames[i])
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(passagelanguages[i])
+		if searchtexts != nil {
+			fconnection.writeToFile(delimiter)
+			fconnection.writeToFile(searchtexts[i])
+		}
 		fconnection.writeToFile("\n")
 	}
 }
@@ -2096,18 +992,6 @@ func removeDuplicatesUnordered(elements []string) []string {
 	return result
 }
 
-func stringcleaning(text string) string {
-	tagsRegExp := regexp.MustCompile(`<[/]*[^>]*>`)
-	reInsideWhtsp := regexp.MustCompile(`[\s\p{Zs}]{2,}`)
-	result := text
-	result = strings.Replace(result, "\n", " ", -1)
-	result = strings.Replace(result, "#", "", -1)
-	result = tagsRegExp.ReplaceAllString(result, "")
-	result = strings.TrimSpace(result)
-	result = reInsideWhtsp.ReplaceAllString(result, " ")
-	return result
-}
-
 func check(e error) {
 	if e != nil {
 		log.Println("Error:", e.Error())
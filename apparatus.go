@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const apparatusCollectionURN = citeNamespace + "apparatus.v1:"
+
+//ApparatusEntry container for one tei:app of a passage
+type ApparatusEntry struct {
+	App      int       `json:"app"`
+	Passage  string    `json:"passage"`
+	Lemma    string    `json:"lemma"`
+	LemmaWit string    `json:"lemma_wit"`
+	Readings []Reading `json:"readings"`
+}
+
+//Reading container for a tei:rdg of an apparatus entry
+type Reading struct {
+	URN  string `json:"urn"`
+	Text string `json:"text"`
+	Wit  string `json:"wit"`
+	Resp string `json:"resp"`
+}
+
+//collectApparatus adds the tei:app entries of a passage, read from its XML before the element policy is applied.
+func collectApparatus(apparatus []ApparatusEntry, passage string, fragment XMLFragment, breaks map[string]bool) []ApparatusEntry {
+	if fragment.Root == nil {
+		return apparatus
+	}
+	walkOutermost(fragment.Root, func(node *XMLNode) bool {
+		if node.Name != "app" {
+			return false
+		}
+		entry := ApparatusEntry{App: len(apparatus) + 1, Passage: passage}
+		walkOutermost(node, func(child *XMLNode) bool {
+			switch child.Name {
+			case "lem":
+				entry.Lemma = extractText(child.InnerXML(fragment.Document), breaks)
+				entry.LemmaWit = pointerList(child.Attrs["wit"])
+				return true
+			case "rdg":
+				entry.Readings = append(entry.Readings, Reading{
+					URN:  apparatusCollectionURN + strconv.Itoa(entry.App) + "_" + strconv.Itoa(len(entry.Readings)+1),
+					Text: extractText(child.InnerXML(fragment.Document), breaks),
+					Wit:  pointerList(child.Attrs["wit"]),
+					Resp: pointerList(child.Attrs["resp"]),
+				})
+				return true
+			}
+			return false
+		})
+		apparatus = append(apparatus, entry)
+		return true
+	})
+	return apparatus
+}
+
+//pointerList turns a list of TEI pointers like "#A #B" into sigla "A B".
+func pointerList(value string) string {
+	sigla := []string{}
+	for _, v := range strings.Fields(value) {
+		sigla = append(sigla, strings.TrimPrefix(v, "#"))
+	}
+	return strings.Join(sigla, " ")
+}
+
+//apparatusCollection turns every reading into an object of the apparatus collection.
+func apparatusCollection(apparatus []ApparatusEntry) CiteCollection {
+	collection := CiteCollection{
+		URN:         apparatusCollectionURN,
+		Description: "Critical apparatus of the TEI editions",
+		Labelling:   "reading",
+		License:     defaultLicense,
+		Model:       CiteDataModel{Model: citeNamespace + "datamodels.v1:apparatus", Label: "Critical apparatus", Description: "Readings of the tei:app entries of a passage, grouped by entry"},
+		Properties: []CiteProperty{
+			{Name: "urn", Label: "Reading", Type: "Cite2Urn"},
+			{Name: "passage", Label: "Passage", Type: "CtsUrn"},
+			{Name: "app", Label: "Apparatus entry", Type: "Number"},
+			{Name: "lemma", Label: "Lemma", Type: "String"},
+			{Name: "lemmawit", Label: "Witnesses of the lemma", Type: "String"},
+			{Name: "reading", Label: "Reading", Type: "String"},
+			{Name: "wit", Label: "Witnesses", Type: "String"},
+			{Name: "resp", Label: "Responsibility", Type: "String"},
+		},
+	}
+	for _, entry := range apparatus {
+		for _, v := range entry.Readings {
+			collection.Rows = append(collection.Rows, []string{v.URN, entry.Passage, strconv.Itoa(entry.App), entry.Lemma, entry.LemmaWit, v.Text, v.Wit, v.Resp})
+		}
+	}
+	return collection
+}
+
+func writeApparatusJSON(outputFile string, apparatus []ApparatusEntry) {
+	jsonapparatus, err1 := json.Marshal(apparatus)
+	check(err1)
+	f, err2 := os.Create(outputFile)
+	check(err2)
+	defer f.Close()
+	_, err := f.WriteString(string(jsonapparatus))
+	check(err)
+}
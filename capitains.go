@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+//CTSLabel container for a language-tagged CapiTainS label
+type CTSLabel struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Text string `xml:",chardata"`
+}
+
+//CTSTextgroup container for the ti:textgroup of a __cts__.xml
+type CTSTextgroup struct {
+	URN       string     `xml:"urn,attr"`
+	GroupName []CTSLabel `xml:"groupname"`
+}
+
+//CTSWork container for the ti:work of a __cts__.xml
+type CTSWork struct {
+	URN          string       `xml:"urn,attr"`
+	Lang         string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title        []CTSLabel   `xml:"title"`
+	Editions     []CTSVersion `xml:"edition"`
+	Translations []CTSVersion `xml:"translation"`
+	Commentaries []CTSVersion `xml:"commentary"`
+}
+
+//CTSVersion container for a ti:edition, ti:translation or ti:commentary
+type CTSVersion struct {
+	URN         string       `xml:"urn,attr"`
+	Lang        string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Label       []CTSLabel   `xml:"label"`
+	Description []CTSLabel   `xml:"description"`
+	Exemplars   []CTSVersion `xml:"exemplar"`
+}
+
+//CapitainsInfo holds the catalog fields found in the __cts__.xml files of a text. Fields are empty when no metadata was found.
+type CapitainsInfo struct {
+	GroupName     string
+	WorkTitle     string
+	VersionLabel  string
+	ExemplarLabel string
+	Description   string
+	VersionType   string
+}
+
+//CapitainsIndex caches the parsed __cts__.xml files of a corpus by directory.
+type CapitainsIndex struct {
+	textgroups map[string]*CTSTextgroup
+	works      map[string]*CTSWork
+}
+
+func newCapitainsIndex() *CapitainsIndex {
+	return &CapitainsIndex{textgroups: make(map[string]*CTSTextgroup), works: make(map[string]*CTSWork)}
+}
+
+//lookup returns the metadata of the version or exemplar urn stored in file. The work __cts__.xml is expected next to the file, the textgroup one in the parent folder.
+func (c *CapitainsIndex) lookup(file, urn string) CapitainsInfo {
+	var info CapitainsInfo
+	workDir := filepath.Dir(file)
+	if group := c.textgroup(filepath.Dir(workDir)); group != nil {
+		info.GroupName = preferredLabel(group.GroupName)
+	}
+	work := c.work(workDir)
+	if work == nil {
+		return info
+	}
+	info.WorkTitle = preferredLabel(work.Title)
+	versions := map[string][]CTSVersion{"edition": work.Editions, "translation": work.Translations, "commentary": work.Commentaries}
+	for kind, list := range versions {
+		for _, version := range list {
+			if sameURN(version.URN, urn) {
+				info.VersionType = kind
+				info.VersionLabel = preferredLabel(version.Label)
+				info.Description = preferredLabel(version.Description)
+			}
+			for _, exemplar := range version.Exemplars {
+				if sameURN(exemplar.URN, urn) {
+					info.VersionType = kind
+					info.VersionLabel = preferredLabel(version.Label)
+					info.ExemplarLabel = preferredLabel(exemplar.Label)
+					info.Description = preferredLabel(exemplar.Description)
+				}
+			}
+		}
+	}
+	return info
+}
+
+func (c *CapitainsIndex) textgroup(dir string) *CTSTextgroup {
+	if group, ok := c.textgroups[dir]; ok {
+		return group
+	}
+	var group *CTSTextgroup
+	if byteValue, ok := readCTSFile(dir); ok {
+		group = &CTSTextgroup{}
+		if xml.Unmarshal(byteValue, group) != nil {
+			group = nil
+		}
+	}
+	c.textgroups[dir] = group
+	return group
+}
+
+func (c *CapitainsIndex) work(dir string) *CTSWork {
+	if work, ok := c.works[dir]; ok {
+		return work
+	}
+	var work *CTSWork
+	if byteValue, ok := readCTSFile(dir); ok {
+		work = &CTSWork{}
+		if xml.Unmarshal(byteValue, work) != nil {
+			work = nil
+		}
+	}
+	c.works[dir] = work
+	return work
+}
+
+func readCTSFile(dir string) ([]byte, bool) {
+	byteValue, err := ioutil.ReadFile(filepath.Join(dir, "__cts__.xml"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			check(err)
+		}
+		return nil, false
+	}
+	return byteValue, true
+}
+
+//sameURN reports whether a and b are the same CTS URN, ignoring surrounding whitespace and a trailing colon.
+func sameURN(a, b string) bool {
+	parsedA, errA := ctsurn.Parse(strings.TrimSpace(a))
+	parsedB, errB := ctsurn.Parse(strings.TrimSpace(b))
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return parsedA == parsedB
+}
+
+//preferredLabel picks the English label if there is one, otherwise the first.
+func preferredLabel(labels []CTSLabel) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	label := labels[0].Text
+	for _, v := range labels {
+		if v.Lang == "eng" {
+			label = v.Text
+			break
+		}
+	}
+	return collapseWhitespace(label)
+}
+
+//firstNonEmpty returns the first value that is not blank.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+//versionURN returns the URN of the version or exemplar of the work __cts__.xml next to file that is named like the file, or the only one the work lists.
+func (c *CapitainsIndex) versionURN(file, name string) string {
+	work := c.work(filepath.Dir(file))
+	if work == nil {
+		return ""
+	}
+	var urns []string
+	for _, list := range [][]CTSVersion{work.Editions, work.Translations, work.Commentaries} {
+		for _, version := range list {
+			urns = append(urns, version.URN)
+			for _, exemplar := range version.Exemplars {
+				urns = append(urns, exemplar.URN)
+			}
+		}
+	}
+	for _, v := range urns {
+		if parsed, err := ctsurn.Parse(v); err == nil && parsed.WorkComponent() == name {
+			return v
+		}
+	}
+	if len(urns) == 1 {
+		return urns[0]
+	}
+	return ""
+}
+
+//namespace returns the CTS namespace of the work __cts__.xml next to file, e.g. urn:cts:latinLit:
+func (c *CapitainsIndex) namespace(file string) string {
+	work := c.work(filepath.Dir(file))
+	if work == nil {
+		return ""
+	}
+	parsed, err := ctsurn.Parse(work.URN)
+	if err != nil {
+		return ""
+	}
+	return "urn:cts:" + parsed.Namespace + ":"
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+//CEXLibrary container for the content of a CEX file: the catalog and passages of its texts, its CITE collections and relations, and the blocks that are only passed through.
+type CEXLibrary struct {
+	Version     string
+	Library     map[string]string
+	Catalog     CTSCatalog
+	Identifiers []string
+	Texts       []string
+	Collections []CiteCollection
+	Relations   CiteRelations
+	DataModels  []CiteDataModel
+}
+
+//cexCatalogColumns are the #!ctscatalog columns in the order of CTSCatalog.
+var cexCatalogColumns = []string{"urn", "citationscheme", "groupname", "worktitle", "versionlabel", "exemplarlabel", "online", "language"}
+
+//cexCatalogAliases maps other names of #!ctscatalog columns to cexCatalogColumns. The CEX specification names the language column lang.
+var cexCatalogAliases = map[string]string{"lang": "language"}
+
+//readCEX parses a CEX file. The delimiter is taken from the block headers, blocks that are not known are skipped.
+func readCEX(file string) (CEXLibrary, error) {
+	library := CEXLibrary{Library: make(map[string]string)}
+	byteValue, err := ioutil.ReadFile(file)
+	if err != nil {
+		return library, err
+	}
+	lines := strings.Split(strings.Replace(string(byteValue), "\r\n", "\n", -1), "\n")
+	delimiter := cexDelimiter(lines)
+	block := ""
+	var header []string
+	var citedata *CiteCollection
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#!") {
+			block = strings.TrimSpace(line)
+			header = nil
+			citedata = nil
+			continue
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fields := strings.Split(line, delimiter)
+		switch block {
+		case "#!cexversion":
+			library.Version = strings.TrimSpace(line)
+		case "#!citelibrary":
+			if len(fields) > 1 {
+				library.Library[fields[0]] = strings.Join(fields[1:], delimiter)
+			}
+		case "#!ctscatalog":
+			if header == nil {
+				header = fields
+				continue
+			}
+			library.addCatalogRow(header, fields)
+		case "#!ctsdata":
+			cut := strings.Index(line, delimiter)
+			if cut == -1 {
+				return library, fmt.Errorf("%s: line without text in #!ctsdata: %s", file, line)
+			}
+			library.Identifiers = append(library.Identifiers, line[:cut])
+			library.Texts = append(library.Texts, line[cut+len(delimiter):])
+		case "#!citecollections":
+			if header == nil {
+				header = fields
+				continue
+			}
+			collection := CiteCollection{URN: fields[0]}
+			if len(fields) > 1 {
+				collection.Description = fields[1]
+			}
+			if len(fields) > 2 {
+				collection.Labelling = propertyName(fields[2])
+			}
+			if len(fields) > 3 {
+				collection.Ordering = propertyName(fields[3])
+			}
+			if len(fields) > 4 {
+				collection.License = fields[4]
+			}
+			library.Collections = append(library.Collections, collection)
+		case "#!citeproperties":
+			if header == nil {
+				header = fields
+				continue
+			}
+			property := CiteProperty{Name: propertyName(fields[0])}
+			if len(fields) > 1 {
+				property.Label = fields[1]
+			}
+			if len(fields) > 2 {
+				property.Type = fields[2]
+			}
+			if len(fields) > 3 {
+				property.Authority = fields[3]
+			}
+			if c := library.collection(fields[0]); c != nil {
+				c.Properties = append(c.Properties, property)
+			}
+		case "#!citedata":
+			if header == nil {
+				header = fields
+				continue
+			}
+			if citedata == nil {
+				if citedata = library.collection(fields[0]); citedata == nil {
+					return library, fmt.Errorf("%s: #!citedata for %s has no collection in #!citecollections", file, fields[0])
+				}
+			}
+			citedata.Rows = append(citedata.Rows, fields)
+		case "#!relations":
+			if len(fields) != 3 {
+				return library, fmt.Errorf("%s: relation without subject, verb and object: %s", file, line)
+			}
+			library.Relations.add(fields[0], fields[1], fields[2])
+		case "#!datamodels":
+			if header == nil {
+				header = fields
+				continue
+			}
+			model := CiteDataModel{Collection: fields[0]}
+			if len(fields) > 1 {
+				model.Model = fields[1]
+			}
+			if len(fields) > 2 {
+				model.Label = fields[2]
+			}
+			if len(fields) > 3 {
+				model.Description = fields[3]
+			}
+			library.DataModels = append(library.DataModels, model)
+		}
+	}
+	return library, nil
+}
+
+//addCatalogRow adds a #!ctscatalog row. The URN is written without a trailing colon, so that it matches the text of the passages.
+func (l *CEXLibrary) addCatalogRow(header, fields []string) {
+	values := make(map[string]string)
+	for i, v := range header {
+		if i < len(fields) {
+			column := strings.ToLower(strings.TrimSpace(v))
+			values[firstNonEmpty(cexCatalogAliases[column], column)] = fields[i]
+		}
+	}
+	if urn, err := ctsurn.Parse(values["urn"]); err == nil {
+		values["urn"] = urn.String()
+	}
+	columns := []*[]string{&l.Catalog.URN, &l.Catalog.CitationScheme, &l.Catalog.GroupName, &l.Catalog.WorkTitle, &l.Catalog.VersionLabel, &l.Catalog.ExemplarLabel, &l.Catalog.Online, &l.Catalog.Language}
+	for i, column := range columns {
+		*column = append(*column, values[cexCatalogColumns[i]])
+	}
+	l.Catalog.Description = append(l.Catalog.Description, "")
+	l.Catalog.VersionType = append(l.Catalog.VersionType, "")
+	l.Catalog.Contributors = append(l.Catalog.Contributors, JSONContr{})
+}
+
+//collection returns the collection an object or property URN belongs to, e.g. urn:cite2:teitocex:notes.v1: for urn:cite2:teitocex:notes.v1:3 or urn:cite2:teitocex:notes.v1.text:
+func (l *CEXLibrary) collection(urn string) *CiteCollection {
+	for i, c := range l.Collections {
+		base := strings.TrimSuffix(c.URN, ":")
+		if strings.HasPrefix(urn, base+":") || strings.HasPrefix(urn, base+".") {
+			return &l.Collections[i]
+		}
+	}
+	return nil
+}
+
+//propertyName returns the name of a property URN, e.g. text for urn:cite2:teitocex:notes.v1.text:
+func propertyName(urn string) string {
+	if strings.TrimSpace(urn) == "" {
+		return ""
+	}
+	urn = strings.TrimSuffix(urn, ":")
+	return urn[strings.LastIndex(urn, ".")+1:]
+}
+
+//citationTreeFromPassages rebuilds the citation tree of passages read from a CEX file. The containers of every passage are added before it, their levels are named by the citation scheme of the catalog.
+func citationTreeFromPassages(ctscatalog CTSCatalog, identifiers []string) CitationTree {
+	var tree CitationTree
+	schemes := make(map[string][]string)
+	for i, v := range ctscatalog.URN {
+		schemes[v] = strings.Split(ctscatalog.CitationScheme[i], ",")
+	}
+	seen := make(map[string]bool)
+	for _, identifier := range identifiers {
+		passage, err := ctsurn.Parse(identifier)
+		if err != nil || passage.IsRange() {
+			continue
+		}
+		version := passage.Text()
+		levels := schemes[version.String()]
+		reference := strings.Split(passage.Start.Reference, ".")
+		for depth := 1; depth <= len(reference); depth++ {
+			node := version.WithPassage(reference[:depth]...).String()
+			if seen[node] {
+				continue
+			}
+			seen[node] = true
+			label := ""
+			if depth <= len(levels) {
+				label = levels[depth-1]
+			}
+			tree.add(version, reference[:depth], label, depth == len(reference))
+		}
+	}
+	return tree
+}
+
+//catalogLanguages returns the catalog language of the text of every passage.
+func catalogLanguages(ctscatalog CTSCatalog, identifiers []string) []string {
+	languages := make(map[string]string)
+	for i, v := range ctscatalog.URN {
+		languages[v] = ctscatalog.Language[i]
+	}
+	var result []string
+	for _, identifier := range identifiers {
+		passage, err := ctsurn.Parse(identifier)
+		if err != nil {
+			result = append(result, "")
+			continue
+		}
+		result = append(result, languages[passage.Text().String()])
+	}
+	return result
+}
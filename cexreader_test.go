@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCEXRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teitocex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	citelibrary := CiteLibrary{Name: "Test library", URN: citeNamespace + "library.v1:test", License: defaultLicense}
+	ctscatalog := CTSCatalog{
+		URN:            []string{"urn:cts:greekLit:tlg0012.tlg001.perseus-grc2"},
+		CitationScheme: []string{"book,line"},
+		GroupName:      []string{"Homer"},
+		WorkTitle:      []string{"Iliad"},
+		VersionLabel:   []string{"Iliad (Greek)"},
+		ExemplarLabel:  []string{""},
+		Online:         []string{"True"},
+		Language:       []string{"grc"},
+		Description:    []string{""},
+		VersionType:    []string{""},
+		Contributors:   []JSONContr{{}},
+	}
+	identifiers := []string{"urn:cts:greekLit:tlg0012.tlg001.perseus-grc2:1.1", "urn:cts:greekLit:tlg0012.tlg001.perseus-grc2:1.2"}
+	texts := []string{`μῆνιν ἄειδε θεὰ #1 "Πηληϊάδεω" \ Ἀχιλῆος`, "οὐλομένην | ἣ μυρί᾽ Ἀχαιοῖς ἄλγε᾽ ἔθηκε"}
+	var entities EntityCollection
+	entities.collect(identifiers[0], parseFragment(`<persName ref="#achilles">Ἀχιλῆος</persName>`), defaultElementPolicy(), Options{}.breakElements())
+	if entities.Ref[0] != "#achilles" {
+		t.Errorf("entity ref is %q, want #achilles as written", entities.Ref[0])
+	}
+	collection := entities.citeCollection()
+	collection.Rows = append(collection.Rows, []string{entitiesCollectionURN + "2", identifiers[1], "rs", "#!citedata", "#x", "", "", ""})
+	collections := []CiteCollection{collection}
+	var relations CiteRelations
+	entities.relations(&relations)
+	datamodels := dataModels(nil, collections)
+
+	options := Options{}
+	delimiter := options.delimiter(cexValues(citelibrary, ctscatalog, identifiers, texts, collections, relations, datamodels)...)
+	if delimiter == defaultDelimiter {
+		t.Fatalf("the delimiter %q occurs in the values", delimiter)
+	}
+	file := filepath.Join(dir, "library.cex")
+	writeCEX(file, delimiter, citelibrary, ctscatalog, identifiers, texts, collections, relations, datamodels)
+
+	library, err := readCEX(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := options.citeLibrary(library)
+	if read != citelibrary {
+		t.Errorf("read library %+v, want %+v", read, citelibrary)
+	}
+	if !reflect.DeepEqual(library.Catalog, ctscatalog) {
+		t.Errorf("read catalog %+v, want %+v", library.Catalog, ctscatalog)
+	}
+	if !reflect.DeepEqual(library.Identifiers, identifiers) || !reflect.DeepEqual(library.Texts, texts) {
+		t.Errorf("read passages %q %q, want %q %q", library.Identifiers, library.Texts, identifiers, texts)
+	}
+	// the data model of a collection is written to #!datamodels only
+	collection.Model = CiteDataModel{}
+	if !reflect.DeepEqual(library.Collections, []CiteCollection{collection}) {
+		t.Errorf("read collections %+v, want %+v", library.Collections, collection)
+	}
+	if !reflect.DeepEqual(library.Relations, relations) {
+		t.Errorf("read relations %+v, want %+v", library.Relations, relations)
+	}
+	if !reflect.DeepEqual(library.DataModels, datamodels) {
+		t.Errorf("read data models %+v, want %+v", library.DataModels, datamodels)
+	}
+}
+
+//foreignCEX is a library in the form other tools write it, with an ordering property and an authority list.
+const foreignCEX = `#!cexversion
+3.0
+
+#!citelibrary
+name#Homer Multitext sample
+urn#urn:cite2:hmt:cex.2020a:sample
+license#CC Attribution Share Alike
+
+#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+
+#!citecollections
+URN#Description#Labelling property#Ordering property#License
+urn:cite2:hmt:msA.v1:#Pages of the Venetus A manuscript#urn:cite2:hmt:msA.v1.label:#urn:cite2:hmt:msA.v1.sequence:#CC-attribution-share-alike
+
+#!citeproperties
+Property#Label#Type#Authority list
+urn:cite2:hmt:msA.v1.urn:#URN#Cite2Urn#
+urn:cite2:hmt:msA.v1.sequence:#Page sequence#Number#
+urn:cite2:hmt:msA.v1.rv:#Recto or Verso#String#recto,verso
+urn:cite2:hmt:msA.v1.label:#Label#String#
+
+#!citedata
+urn#sequence#rv#label
+urn:cite2:hmt:msA.v1:12r#1#recto#Venetus A (Marciana 454 = 822), folio 12, recto
+urn:cite2:hmt:msA.v1:12v#2#verso#Venetus A (Marciana 454 = 822), folio 12, verso
+
+#!relations
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#urn:cite2:cite:verbs.v1:appearsOn#urn:cite2:hmt:msA.v1:12r
+
+#!datamodels
+Collection#Model#Label#Description
+urn:cite2:hmt:msA.v1:#urn:cite2:cite:datamodels.v1:tbsmodel#Text-bearing surface#Pages of a manuscript
+`
+
+func TestForeignCEXPassThrough(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teitocex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "hmt.cex")
+	if err := ioutil.WriteFile(input, []byte(foreignCEX), 0600); err != nil {
+		t.Fatal(err)
+	}
+	library, err := readCEX(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(library.Collections) != 1 {
+		t.Fatalf("read %d collections, want 1", len(library.Collections))
+	}
+	collection := library.Collections[0]
+	if collection.Labelling != "label" || collection.Ordering != "sequence" {
+		t.Errorf("read labelling %q and ordering %q, want label and sequence", collection.Labelling, collection.Ordering)
+	}
+	if len(collection.Properties) != 4 || collection.Properties[2].Authority != "recto,verso" {
+		t.Errorf("read properties %+v, want the authority list recto,verso for rv", collection.Properties)
+	}
+
+	options := Options{}
+	citelibrary := options.citeLibrary(library)
+	output := filepath.Join(dir, "out.cex")
+	writeCEX(output, "#", citelibrary, library.Catalog, library.Identifiers, library.Texts, library.Collections, library.Relations, library.DataModels)
+	written, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"urn:cite2:hmt:msA.v1:#Pages of the Venetus A manuscript#urn:cite2:hmt:msA.v1.label:#urn:cite2:hmt:msA.v1.sequence:#CC-attribution-share-alike",
+		"urn:cite2:hmt:msA.v1.rv:#Recto or Verso#String#recto,verso",
+		"urn:cite2:hmt:msA.v1:12v#2#verso#Venetus A (Marciana 454 = 822), folio 12, verso",
+	} {
+		if !strings.Contains(string(written), line+"\n") {
+			t.Errorf("written CEX lacks %q", line)
+		}
+	}
+	reread, err := readCEX(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reread.Collections, library.Collections) || !reflect.DeepEqual(reread.Relations, library.Relations) || !reflect.DeepEqual(reread.DataModels, library.DataModels) {
+		t.Errorf("written CEX reads back as %+v, want %+v", reread.Collections, library.Collections)
+	}
+	if !reflect.DeepEqual(reread.Catalog, library.Catalog) || !reflect.DeepEqual(reread.Texts, library.Texts) || options.citeLibrary(reread) != citelibrary {
+		t.Errorf("written CEX reads back with catalog %+v, want %+v", reread.Catalog, library.Catalog)
+	}
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+const xmlNamespaceURL = "http://www.w3.org/XML/1998/namespace"
+
+//XMLNode is a lightweight element tree used to resolve citation XPaths. It keeps the byte offsets of the element and of its inner XML so that passages can be cut from the original document.
+type XMLNode struct {
+	Name       string
+	Attrs      map[string]string
+	Parent     *XMLNode
+	Children   []*XMLNode
+	OuterStart int64
+	InnerStart int64
+	InnerEnd   int64
+	OuterEnd   int64
+}
+
+//InnerXML returns the raw inner XML of the node in the document it was parsed from.
+func (n *XMLNode) InnerXML(document []byte) string {
+	return string(document[n.InnerStart:n.InnerEnd])
+}
+
+//Attr returns the value of an attribute and whether it is set. Attributes are keyed by their local name, attributes in the XML namespace are prefixed with "xml:".
+func (n *XMLNode) Attr(name string) (string, bool) {
+	value, ok := n.Attrs[name]
+	return value, ok
+}
+
+//parseXMLTree reads a document into an XMLNode tree and returns the root element.
+func parseXMLTree(document []byte) (*XMLNode, error) {
+	decoder := newLenientDecoder(document)
+	var root, current *XMLNode
+	for {
+		before := decoder.InputOffset()
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch se := t.(type) {
+		case xml.StartElement:
+			node := &XMLNode{Name: se.Name.Local, Attrs: make(map[string]string), Parent: current, OuterStart: before, InnerStart: decoder.InputOffset()}
+			for _, attr := range se.Attr {
+				if attr.Name.Space == xmlNamespaceURL {
+					node.Attrs["xml:"+attr.Name.Local] = attr.Value
+					continue
+				}
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			if current == nil {
+				root = node
+			} else {
+				current.Children = append(current.Children, node)
+			}
+			current = node
+		case xml.EndElement:
+			current.InnerEnd = before
+			current.OuterEnd = decoder.InputOffset()
+			current = current.Parent
+		}
+	}
+	if root == nil {
+		return nil, errors.New("document has no root element")
+	}
+	return root, nil
+}
+
+//XMLFragment is the XML of a passage wrapped in a <fragment> element and parsed once for all extractors. Root is nil if the XML is not well-formed.
+type XMLFragment struct {
+	Inner    string
+	Document []byte
+	Root     *XMLNode
+}
+
+//parseFragment parses the inner XML of a passage.
+func parseFragment(inner string) XMLFragment {
+	fragment := XMLFragment{Inner: inner, Document: []byte("<fragment>" + inner + "</fragment>")}
+	if root, err := parseXMLTree(fragment.Document); err == nil {
+		fragment.Root = root
+	}
+	return fragment
+}
+
+//newLenientDecoder returns a decoder that accepts the HTML and undeclared DTD entities found in older TEI files.
+func newLenientDecoder(document []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+var internalSubsetRegExp = regexp.MustCompile(`(?s)<!DOCTYPE[^\[>]*\[(.*?)\]\s*>`)
+var entityDeclRegExp = regexp.MustCompile(`<!ENTITY\s+([^\s%]+)\s+(?:"([^"]*)"|'([^']*)'|(SYSTEM|PUBLIC))`)
+var entityRefRegExp = regexp.MustCompile(`&([A-Za-z_][\w.-]*);`)
+
+//xmlEntities are predefined by XML and cannot be redeclared.
+var xmlEntities = map[string]bool{"amp": true, "lt": true, "gt": true, "quot": true, "apos": true}
+
+//expandDeclaredEntities replaces references to the general entities declared in the internal subset of the DOCTYPE, as legacy TEI.2 files use them, with their replacement text. Entity values may refer to other declared entities. External entities cannot be read and are left as they are, with a warning.
+func expandDeclaredEntities(document []byte, file string) []byte {
+	subset := internalSubsetRegExp.FindSubmatchIndex(document)
+	if subset == nil {
+		return document
+	}
+	entities := make(map[string]string)
+	for _, decl := range entityDeclRegExp.FindAllStringSubmatch(string(document[subset[2]:subset[3]]), -1) {
+		if xmlEntities[decl[1]] {
+			continue
+		}
+		if decl[4] != "" {
+			fmt.Println("Warning:", path.Base(file), "declares the external entity", decl[1], "which is not expanded.")
+			continue
+		}
+		entities[decl[1]] = decl[2] + decl[3]
+	}
+	if len(entities) == 0 {
+		return document
+	}
+	body := string(document[subset[1]:])
+	// a few passes resolve entities used in the values of other entities, without looping on recursive ones
+	for i := 0; i < 8; i++ {
+		expanded := entityRefRegExp.ReplaceAllStringFunc(body, func(ref string) string {
+			if value, ok := entities[ref[1:len(ref)-1]]; ok {
+				return value
+			}
+			return ref
+		})
+		if expanded == body {
+			break
+		}
+		body = expanded
+	}
+	return append(append([]byte{}, document[:subset[1]]...), body...)
+}
+
+//xpathPredicate is a single attribute test such as [@n='$1'] or [@type='edition'].
+type xpathPredicate struct {
+	Attr  string
+	Value string
+	Param int
+}
+
+//xpathStep is one location step of a citation XPath.
+type xpathStep struct {
+	Descendant bool
+	Name       string
+	Predicates []xpathPredicate
+}
+
+//CitationPattern is a parsed cRefPattern replacementPattern. If Scope is set, the pattern only resolves inside that version div, and the @type of the version div is not tested, so that a refsDecl written for the edition also cites a translation in the same file. If Skipped is set, the elements that are not cited because their citation attribute is empty are recorded in it.
+type CitationPattern struct {
+	XPath   string
+	Steps   []xpathStep
+	Params  int
+	Scope   *XMLNode
+	Skipped map[*XMLNode]string
+}
+
+//CitedNode is a citable element together with its citation components, the label of its level and whether it is a passage rather than a container. Passages cut out of an element (e.g. between milestones) keep that element as Node and carry their own XML as Fragment.
+type CitedNode struct {
+	Node      *XMLNode
+	Reference []string
+	Label     string
+	Leaf      bool
+	Fragment  string
+}
+
+//innerXML returns the XML of the cited passage.
+func (c CitedNode) innerXML(document []byte) string {
+	if c.Fragment != "" {
+		return c.Fragment
+	}
+	return c.Node.InnerXML(document)
+}
+
+//parseCitationXPath turns a replacementPattern like #xpath(/tei:TEI/tei:text/tei:body/tei:div//tei:l[@n='$1']) into a CitationPattern.
+func parseCitationXPath(replacement string) (CitationPattern, error) {
+	xpath := strings.TrimSpace(replacement)
+	xpath = strings.TrimPrefix(xpath, "#xpath(")
+	xpath = strings.TrimSuffix(xpath, ")")
+	xpath = strings.Replace(xpath, `\'`, "'", -1)
+	xpath = strings.Replace(xpath, `\"`, `"`, -1)
+	pattern := CitationPattern{XPath: xpath}
+	if !strings.HasPrefix(xpath, "/") {
+		return pattern, fmt.Errorf("xpath %q is not absolute", xpath)
+	}
+	pos := 0
+	for pos < len(xpath) {
+		step := xpathStep{}
+		switch {
+		case strings.HasPrefix(xpath[pos:], "//"):
+			step.Descendant = true
+			pos += 2
+		case xpath[pos] == '/':
+			pos++
+		default:
+			return pattern, fmt.Errorf("unexpected %q in xpath %q", xpath[pos:], xpath)
+		}
+		end := strings.IndexAny(xpath[pos:], "/[")
+		if end == -1 {
+			end = len(xpath) - pos
+		}
+		name := xpath[pos : pos+end]
+		pos += end
+		if i := strings.Index(name, ":"); i != -1 {
+			name = name[i+1:]
+		}
+		if name == "" {
+			return pattern, fmt.Errorf("empty step in xpath %q", xpath)
+		}
+		step.Name = name
+		for pos < len(xpath) && xpath[pos] == '[' {
+			end := strings.Index(xpath[pos:], "]")
+			if end == -1 {
+				return pattern, fmt.Errorf("unterminated predicate in xpath %q", xpath)
+			}
+			for _, test := range strings.Split(xpath[pos+1:pos+end], " and ") {
+				predicate, err := parsePredicate(test)
+				if err != nil {
+					return pattern, fmt.Errorf("%v in xpath %q", err, xpath)
+				}
+				if predicate.Param > pattern.Params {
+					pattern.Params = predicate.Param
+				}
+				step.Predicates = append(step.Predicates, predicate)
+			}
+			pos += end + 1
+		}
+		pattern.Steps = append(pattern.Steps, step)
+	}
+	if pattern.Params == 0 {
+		return pattern, fmt.Errorf("xpath %q has no $n placeholder", xpath)
+	}
+	return pattern, nil
+}
+
+func parsePredicate(test string) (xpathPredicate, error) {
+	test = strings.TrimSpace(test)
+	parts := strings.SplitN(test, "=", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "@") {
+		return xpathPredicate{}, fmt.Errorf("unsupported predicate [%s]", test)
+	}
+	predicate := xpathPredicate{Attr: strings.TrimSpace(parts[0][1:])}
+	if i := strings.Index(predicate.Attr, ":"); i != -1 && predicate.Attr[:i] != "xml" {
+		predicate.Attr = predicate.Attr[i+1:]
+	}
+	value := strings.TrimSpace(parts[1])
+	if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return xpathPredicate{}, fmt.Errorf("unquoted value in predicate [%s]", test)
+	}
+	value = value[1 : len(value)-1]
+	if strings.HasPrefix(value, "$") {
+		param, err := strconv.Atoi(value[1:])
+		if err != nil || param < 1 {
+			return xpathPredicate{}, fmt.Errorf("invalid placeholder in predicate [%s]", test)
+		}
+		predicate.Param = param
+		return predicate, nil
+	}
+	predicate.Value = value
+	return predicate, nil
+}
+
+//Resolve walks the tree and returns every element matched by the pattern in document order.
+func (p CitationPattern) Resolve(root *XMLNode) []CitedNode {
+	var result []CitedNode
+	bindings := make([]string, p.Params)
+	document := &XMLNode{Children: []*XMLNode{root}}
+	p.resolveStep(document, 0, bindings, &result)
+	return result
+}
+
+func (p CitationPattern) resolveStep(context *XMLNode, index int, bindings []string, result *[]CitedNode) {
+	step := p.Steps[index]
+	visit := func(node *XMLNode) bool {
+		if p.outOfScope(node) {
+			// do not descend into other versions either
+			return true
+		}
+		bound, ok, empty := step.match(node, bindings, node == p.Scope)
+		if empty != "" && p.Skipped != nil {
+			p.Skipped[node] = empty
+		}
+		if !ok {
+			return false
+		}
+		if index == len(p.Steps)-1 {
+			reference := make([]string, len(bound))
+			copy(reference, bound)
+			*result = append(*result, CitedNode{Node: node, Reference: reference})
+		} else {
+			p.resolveStep(node, index+1, bound, result)
+		}
+		return true
+	}
+	for _, child := range context.Children {
+		if step.Descendant {
+			walkOutermost(child, visit)
+		} else {
+			visit(child)
+		}
+	}
+}
+
+//walkOutermost visits node and its descendants, but does not descend below a node for which visit reports a match. This keeps nested elements of the same kind from being cited twice.
+func walkOutermost(node *XMLNode, visit func(*XMLNode) bool) {
+	if visit(node) {
+		return
+	}
+	for _, child := range node.Children {
+		walkOutermost(child, visit)
+	}
+}
+
+//outOfScope reports whether node is a version div other than the scope of the pattern.
+func (p CitationPattern) outOfScope(node *XMLNode) bool {
+	return p.Scope != nil && node != p.Scope && node.Parent == p.Scope.Parent && versionTypes[node.Attrs["type"]]
+}
+
+//match tests node against the step and binds its placeholders. If a placeholder attribute is empty, its name is returned as well.
+func (s xpathStep) match(node *XMLNode, bindings []string, scope bool) ([]string, bool, string) {
+	if s.Name != "*" && s.Name != node.Name {
+		return bindings, false, ""
+	}
+	bound := bindings
+	copied := false
+	for _, predicate := range s.Predicates {
+		value, ok := node.Attr(predicate.Attr)
+		if !ok {
+			return bindings, false, ""
+		}
+		if predicate.Param == 0 {
+			if value != predicate.Value && !(scope && predicate.Attr == "type") {
+				return bindings, false, ""
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return bindings, false, predicate.Attr
+		}
+		if !copied {
+			bound = make([]string, len(bindings))
+			copy(bound, bindings)
+			copied = true
+		}
+		bound[predicate.Param-1] = value
+	}
+	return bound, true, ""
+}
+
+//CitationLevel is one declared cRefPattern, e.g. "book" or "line".
+type CitationLevel struct {
+	Label   string
+	Pattern CitationPattern
+}
+
+//CitationTree records every citable node with its level and parent, so that a book or a chapter can be requested as a whole.
+type CitationTree struct {
+	URN    []string `json:"urn"`
+	Level  []string `json:"level"`
+	Depth  []int    `json:"depth"`
+	Parent []string `json:"parent"`
+	Leaf   []bool   `json:"leaf"`
+}
+
+//parseCitationLevels parses all cRefPatterns of a header and orders them from the outermost to the innermost level. Patterns that cannot be parsed are returned separately.
+func parseCitationLevels(refPatterns []XPathInfo) (levels []CitationLevel, unknown []string) {
+	for _, v := range refPatterns {
+		pattern, err := parseCitationXPath(v.XPathInfo)
+		if err != nil {
+			unknown = append(unknown, v.XPathInfo)
+			continue
+		}
+		levels = append(levels, CitationLevel{Label: v.XPathWhat, Pattern: pattern})
+	}
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Pattern.Params < levels[j].Pattern.Params
+	})
+	return levels, unknown
+}
+
+//SkippedNode is an element that is not cited because its citation attribute is empty.
+type SkippedNode struct {
+	Node *XMLNode
+	Attr string
+}
+
+//resolveCitationHierarchy resolves every level and returns the cited nodes of all levels in document order, containers before their children, together with the skipped elements in document order. A non-nil scope restricts the resolution to that version div.
+func resolveCitationHierarchy(levels []CitationLevel, root *XMLNode, scope *XMLNode) ([]CitedNode, []SkippedNode) {
+	var result []CitedNode
+	skipped := make(map[*XMLNode]string)
+	for i, level := range levels {
+		level.Pattern.Scope = scope
+		level.Pattern.Skipped = skipped
+		for _, cited := range level.Pattern.Resolve(root) {
+			cited.Label = level.Label
+			cited.Leaf = i == len(levels)-1
+			result = append(result, cited)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Node.InnerStart != result[j].Node.InnerStart {
+			return result[i].Node.InnerStart < result[j].Node.InnerStart
+		}
+		return len(result[i].Reference) < len(result[j].Reference)
+	})
+	var skippedNodes []SkippedNode
+	for node, attr := range skipped {
+		skippedNodes = append(skippedNodes, SkippedNode{Node: node, Attr: attr})
+	}
+	sort.Slice(skippedNodes, func(i, j int) bool {
+		return skippedNodes[i].Node.OuterStart < skippedNodes[j].Node.OuterStart
+	})
+	return result, skippedNodes
+}
+
+func (t *CitationTree) add(urn ctsurn.URN, reference []string, label string, leaf bool) {
+	parent := ""
+	if len(reference) > 1 {
+		parent = urn.WithPassage(reference[:len(reference)-1]...).String()
+	}
+	t.URN = append(t.URN, urn.WithPassage(reference...).String())
+	t.Level = append(t.Level, label)
+	t.Depth = append(t.Depth, len(reference))
+	t.Parent = append(t.Parent, parent)
+	t.Leaf = append(t.Leaf, leaf)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCitationXPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		steps   []xpathStep
+		params  int
+	}{
+		{"child steps", "#xpath(/tei:TEI/tei:text/tei:body/tei:div/tei:l[@n='$1'])", []xpathStep{
+			{Name: "TEI"}, {Name: "text"}, {Name: "body"}, {Name: "div"},
+			{Name: "l", Predicates: []xpathPredicate{{Attr: "n", Param: 1}}},
+		}, 1},
+		{"descendant step", "#xpath(/tei:TEI/tei:text/tei:body/tei:div//tei:l[@n='$1'])", []xpathStep{
+			{Name: "TEI"}, {Name: "text"}, {Name: "body"}, {Name: "div"},
+			{Descendant: true, Name: "l", Predicates: []xpathPredicate{{Attr: "n", Param: 1}}},
+		}, 1},
+		{"escaped quotes", `#xpath(/tei:TEI/tei:text/tei:body/tei:div[@type=\'edition\']/tei:div[@n=\'$1\'])`, []xpathStep{
+			{Name: "TEI"}, {Name: "text"}, {Name: "body"},
+			{Name: "div", Predicates: []xpathPredicate{{Attr: "type", Value: "edition"}}},
+			{Name: "div", Predicates: []xpathPredicate{{Attr: "n", Param: 1}}},
+		}, 1},
+		{"double quotes", `#xpath(/tei:TEI/tei:text/tei:body/tei:div[@n="$1"])`, []xpathStep{
+			{Name: "TEI"}, {Name: "text"}, {Name: "body"},
+			{Name: "div", Predicates: []xpathPredicate{{Attr: "n", Param: 1}}},
+		}, 1},
+		{"and predicates", "#xpath(/tei:TEI/tei:text/tei:body/tei:div/tei:div[@type='textpart' and @subtype='book' and @n='$1']/tei:l[@n='$2'])", []xpathStep{
+			{Name: "TEI"}, {Name: "text"}, {Name: "body"}, {Name: "div"},
+			{Name: "div", Predicates: []xpathPredicate{{Attr: "type", Value: "textpart"}, {Attr: "subtype", Value: "book"}, {Attr: "n", Param: 1}}},
+			{Name: "l", Predicates: []xpathPredicate{{Attr: "n", Param: 2}}},
+		}, 2},
+		{"xml attributes keep their prefix", "#xpath(/tei:TEI/tei:text/tei:body/tei:div[@xml:lang='grc']/tei:p[@n='$1'])", []xpathStep{
+			{Name: "TEI"}, {Name: "text"}, {Name: "body"},
+			{Name: "div", Predicates: []xpathPredicate{{Attr: "xml:lang", Value: "grc"}}},
+			{Name: "p", Predicates: []xpathPredicate{{Attr: "n", Param: 1}}},
+		}, 1},
+	}
+	for _, test := range tests {
+		pattern, err := parseCitationXPath(test.pattern)
+		if err != nil {
+			t.Errorf("%s: parseCitationXPath(%q): %v", test.name, test.pattern, err)
+			continue
+		}
+		if !reflect.DeepEqual(pattern.Steps, test.steps) || pattern.Params != test.params {
+			t.Errorf("%s: parseCitationXPath(%q) = %+v with %d params, want %+v with %d", test.name, test.pattern, pattern.Steps, pattern.Params, test.steps, test.params)
+		}
+	}
+}
+
+func TestParseCitationXPathRejects(t *testing.T) {
+	tests := []string{
+		"#xpath(tei:TEI/tei:text/tei:body/tei:div[@n='$1'])",
+		"#xpath(/tei:TEI/tei:text/tei:body/tei:div)",
+		"#xpath(/tei:TEI/tei:text/tei:body/tei:div[position()=$1])",
+		"#xpath(/tei:TEI/tei:text/tei:body/tei:div[@n=$1])",
+		"#xpath(/tei:TEI/tei:text/tei:body/tei:div[@n='$1' or @n='$2'])",
+		"#xpath(/tei:TEI/tei:text/tei:body/tei:div[@n='$1')",
+		"#xpath(/tei:TEI/tei:text/tei:body/tei:div[@n='$0'])",
+		"#xpath(/tei:TEI/tei:text/tei:body///tei:div[@n='$1'])",
+	}
+	for _, test := range tests {
+		if _, err := parseCitationXPath(test); err == nil {
+			t.Errorf("parseCitationXPath(%q) accepted an unsupported pattern", test)
+		}
+	}
+}
+
+const citationTestDocument = `<TEI xmlns="http://www.tei-c.org/ns/1.0"><teiHeader/><text><body>
+<div type="edition" n="urn:cts:greekLit:tlg0012.tlg001.perseus-grc2">
+<div type="textpart" subtype="book" n="1"><l n="1">μῆνιν ἄειδε θεὰ</l><lg><l n="2">οὐλομένην</l></lg><l n="">ἣ μυρί᾽</l></div>
+<div type="textpart" subtype="card" n="x"><l n="1">not a book</l></div>
+<div type="textpart" subtype="book" n="2"><l n="1">ἄλλοι μέν</l></div>
+</div>
+<div type="translation" n="urn:cts:greekLit:tlg0012.tlg001.perseus-eng3">
+<div type="textpart" subtype="book" n="1"><l n="1">Sing, goddess</l></div>
+</div>
+</body></text></TEI>`
+
+func testCitationLevels(t *testing.T, patterns ...XPathInfo) ([]CitationLevel, *XMLNode) {
+	levels, unknown := parseCitationLevels(patterns)
+	if len(unknown) > 0 {
+		t.Fatalf("unknown patterns %q", unknown)
+	}
+	root, err := parseXMLTree([]byte(citationTestDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return levels, root
+}
+
+func citedReferences(passages []CitedNode) []string {
+	var references []string
+	for _, v := range passages {
+		reference := strings.Join(v.Reference, ".")
+		if v.Leaf {
+			reference = reference + "*"
+		}
+		references = append(references, reference)
+	}
+	return references
+}
+
+func TestResolveCitationHierarchy(t *testing.T) {
+	// the line level is declared first, as in many refsDecls
+	levels, root := testCitationLevels(t,
+		XPathInfo{XPathWhat: "line", XPathInfo: "#xpath(/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@subtype='book' and @n='$1']//tei:l[@n='$2'])"},
+		XPathInfo{XPathWhat: "book", XPathInfo: "#xpath(/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@subtype='book' and @n='$1'])"},
+	)
+	if levels[0].Label != "book" || levels[1].Label != "line" {
+		t.Errorf("levels are ordered %s, %s, want book, line", levels[0].Label, levels[1].Label)
+	}
+	passages, skipped := resolveCitationHierarchy(levels, root, nil)
+	want := []string{"1", "1.1*", "1.2*", "2", "2.1*"}
+	if got := citedReferences(passages); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolved %q, want %q", got, want)
+	}
+	if len(skipped) != 1 || skipped[0].Node.Name != "l" || skipped[0].Attr != "n" {
+		t.Errorf("skipped %+v, want the line with an empty @n once", skipped)
+	}
+}
+
+func TestResolveCitationHierarchyScope(t *testing.T) {
+	levels, root := testCitationLevels(t,
+		XPathInfo{XPathWhat: "book", XPathInfo: "#xpath(/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@n='$1'])"},
+		XPathInfo{XPathWhat: "line", XPathInfo: "#xpath(/tei:TEI/tei:text/tei:body/tei:div[@type='edition']/tei:div[@n='$1']/tei:l[@n='$2'])"},
+	)
+	versions := separateVersions(root)
+	if len(versions) != 2 {
+		t.Fatalf("found %d versions, want 2", len(versions))
+	}
+	tests := []struct {
+		scope *XMLNode
+		want  []string
+	}{
+		{versions[0], []string{"1", "1.1*", "x", "x.1*", "2", "2.1*"}},
+		// the refsDecl of the edition also cites the translation
+		{versions[1], []string{"1", "1.1*"}},
+	}
+	for _, test := range tests {
+		passages, _ := resolveCitationHierarchy(levels, root, test.scope)
+		if got := citedReferences(passages); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("resolved %q in %s, want %q", got, test.scope.Attrs["n"], test.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const citeNamespace = "urn:cite2:teitocex:"
+const defaultLicense = "CC-BY-SA 4.0"
+const commentaryModel = "urn:cite2:cite:datamodels.v1:commentarymodel"
+
+//CiteProperty describes one property of a CITE collection.
+type CiteProperty struct {
+	Name      string
+	Label     string
+	Type      string
+	Authority string
+}
+
+//CiteDataModel declares the data model a collection follows, as written to the #!datamodels block.
+type CiteDataModel struct {
+	Collection  string
+	Model       string
+	Label       string
+	Description string
+}
+
+//CiteCollection container for a CITE collection in the form it is written to the #!citecollections, #!citeproperties and #!citedata blocks. The first value of every row is the object URN.
+type CiteCollection struct {
+	URN         string
+	Description string
+	Labelling   string
+	Ordering    string
+	License     string
+	Properties  []CiteProperty
+	Rows        [][]string
+	Model       CiteDataModel
+}
+
+//CiteRelations container for the triples of a #!relations block
+type CiteRelations struct {
+	Subject []string
+	Verb    []string
+	Object  []string
+}
+
+func (r *CiteRelations) add(subject, verb, object string) {
+	r.Subject = append(r.Subject, subject)
+	r.Verb = append(r.Verb, verb)
+	r.Object = append(r.Object, object)
+}
+
+//propertyURN returns the URN of a property of the collection, e.g. urn:cite2:teitocex:notes.v1.text: or an empty string if no property is named.
+func (c CiteCollection) propertyURN(name string) string {
+	if name == "" {
+		return ""
+	}
+	return strings.TrimSuffix(c.URN, ":") + "." + name + ":"
+}
+
+func writeCiteCollections(fconnection fileConnection, delimiter string, collections []CiteCollection) {
+	if len(collections) == 0 {
+		return
+	}
+	fconnection.writeToFile("#!citecollections")
+	fconnection.writeToFile("\n\n")
+	fconnection.writeToFile(strings.Join([]string{"URN", "Description", "Labelling property", "Ordering property", "License"}, delimiter))
+	fconnection.writeToFile("\n")
+	for _, c := range collections {
+		fconnection.writeToFile(c.URN)
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(c.Description)
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(c.propertyURN(c.Labelling))
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(c.propertyURN(c.Ordering))
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(c.License)
+		fconnection.writeToFile("\n")
+	}
+	fconnection.writeToFile("\n")
+
+	fconnection.writeToFile("#!citeproperties")
+	fconnection.writeToFile("\n\n")
+	fconnection.writeToFile(strings.Join([]string{"Property", "Label", "Type", "Authority list"}, delimiter))
+	fconnection.writeToFile("\n")
+	for _, c := range collections {
+		for _, p := range c.Properties {
+			fconnection.writeToFile(c.propertyURN(p.Name))
+			fconnection.writeToFile(delimiter)
+			fconnection.writeToFile(p.Label)
+			fconnection.writeToFile(delimiter)
+			fconnection.writeToFile(p.Type)
+			fconnection.writeToFile(delimiter)
+			fconnection.writeToFile(p.Authority)
+			fconnection.writeToFile("\n")
+		}
+	}
+	fconnection.writeToFile("\n")
+
+	for _, c := range collections {
+		fconnection.writeToFile("#!citedata")
+		fconnection.writeToFile("\n\n")
+		writeCiteRows(fconnection, delimiter, c)
+		fconnection.writeToFile("\n")
+	}
+}
+
+//writeCiteRows writes the header and the rows of a collection, as used in #!citedata blocks and CSV files.
+func writeCiteRows(fconnection fileConnection, delimiter string, c CiteCollection) {
+	names := []string{}
+	for _, p := range c.Properties {
+		names = append(names, p.Name)
+	}
+	fconnection.writeToFile(strings.Join(names, delimiter))
+	fconnection.writeToFile("\n")
+	for _, row := range c.Rows {
+		fconnection.writeToFile(strings.Join(row, delimiter))
+		fconnection.writeToFile("\n")
+	}
+}
+
+func writeRelations(fconnection fileConnection, delimiter string, relations CiteRelations) {
+	if len(relations.Subject) == 0 {
+		return
+	}
+	fconnection.writeToFile("#!relations")
+	fconnection.writeToFile("\n\n")
+	for i := range relations.Subject {
+		fconnection.writeToFile(relations.Subject[i])
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(relations.Verb[i])
+		fconnection.writeToFile(delimiter)
+		fconnection.writeToFile(relations.Object[i])
+		fconnection.writeToFile("\n")
+	}
+	fconnection.writeToFile("\n")
+}
+
+//dataModels returns the declared data models followed by those of the collections that are not declared yet.
+func dataModels(declared []CiteDataModel, collections []CiteCollection) []CiteDataModel {
+	result := append([]CiteDataModel{}, declared...)
+	known := make(map[string]bool)
+	for _, v := range declared {
+		known[v.Collection] = true
+	}
+	for _, c := range collections {
+		if c.Model.Model == "" || known[c.URN] {
+			continue
+		}
+		known[c.URN] = true
+		model := c.Model
+		model.Collection = c.URN
+		result = append(result, model)
+	}
+	return result
+}
+
+func writeDataModels(fconnection fileConnection, delimiter string, datamodels []CiteDataModel) {
+	if len(datamodels) == 0 {
+		return
+	}
+	fconnection.writeToFile("#!datamodels")
+	fconnection.writeToFile("\n\n")
+	fconnection.writeToFile(strings.Join([]string{"Collection", "Model", "Label", "Description"}, delimiter))
+	fconnection.writeToFile("\n")
+	for _, v := range datamodels {
+		fconnection.writeToFile(strings.Join([]string{v.Collection, v.Model, v.Label, v.Description}, delimiter))
+		fconnection.writeToFile("\n")
+	}
+	fconnection.writeToFile("\n")
+}
+
+//sideFile returns the name of an additional output file next to outputFile, e.g. corpus_notes.csv for corpus.csv.
+func sideFile(outputFile, name, ext string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + "_" + name + ext
+}
+
+func writeCiteCSV(outputFile string, delimiter string, collection CiteCollection) {
+	f, err := os.Create(outputFile)
+	check(err)
+	defer f.Close()
+	writeCiteRows(fileConnection{f}, delimiter, collection)
+}
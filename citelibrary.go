@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var cite2ComponentRegExp = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+var notCite2ComponentRegExp = regexp.MustCompile(`[^\p{L}\p{N}_-]+`)
+
+//CiteLibrary container for the name, URN and license of the #!citelibrary block
+type CiteLibrary struct {
+	Name    string
+	URN     string
+	License string
+}
+
+//citeLibrary returns the library set with -LibraryName, -LibraryURN and -License. Values that are not set are taken from the CEX input, or else derived from the folder of the corpus.
+func (o Options) citeLibrary(input CEXLibrary) CiteLibrary {
+	corpus := "corpus"
+	if dir, err := os.Getwd(); err == nil {
+		corpus = filepath.Base(dir)
+	}
+	id := strings.Trim(notCite2ComponentRegExp.ReplaceAllString(corpus, "-"), "-")
+	if id == "" {
+		id = "corpus"
+	}
+	return CiteLibrary{
+		Name:    firstNonEmpty(o.LibraryName, input.Library["name"], "CEX library of "+corpus+" converted by TEItoCEX"),
+		URN:     firstNonEmpty(o.LibraryURN, input.Library["urn"], citeNamespace+"library.v1:"+id),
+		License: firstNonEmpty(o.License, input.Library["license"], defaultLicense),
+	}
+}
+
+//validCite2URN checks that urn has the form urn:cite2:namespace:collection.version:object, where the version and the object are optional.
+func validCite2URN(urn string) error {
+	parts := strings.Split(urn, ":")
+	if len(parts) != 5 || parts[0] != "urn" || parts[1] != "cite2" {
+		return fmt.Errorf("%q is not a CITE2 URN of the form urn:cite2:namespace:collection.version:object", urn)
+	}
+	if !cite2ComponentRegExp.MatchString(parts[2]) {
+		return fmt.Errorf("%q has an invalid namespace", urn)
+	}
+	collection := strings.Split(parts[3], ".")
+	if len(collection) > 3 {
+		return fmt.Errorf("%q has too many collection components", urn)
+	}
+	for _, v := range collection {
+		if !cite2ComponentRegExp.MatchString(v) {
+			return fmt.Errorf("%q has an invalid collection component", urn)
+		}
+	}
+	return nil
+}
+
+func writeCiteLibrary(fconnection fileConnection, delimiter string, library CiteLibrary) {
+	fconnection.writeToFile("#!citelibrary")
+	fconnection.writeToFile("\n\n")
+	fconnection.writeToFile("name" + delimiter + library.Name)
+	fconnection.writeToFile("\n")
+	fconnection.writeToFile("urn" + delimiter + library.URN)
+	fconnection.writeToFile("\n")
+	fconnection.writeToFile("license" + delimiter + library.License)
+	fconnection.writeToFile("\n\n")
+}
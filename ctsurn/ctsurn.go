@@ -0,0 +1,197 @@
+//Package ctsurn parses, validates and formats CTS URNs like urn:cts:greekLit:tlg0012.tlg001.perseus-grc2:1.1-1.5 or urn:cts:greekLit:tlg0012.tlg001.perseus-grc2:1.1@μῆνιν[1].
+package ctsurn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//Position is one end of a passage component: a citation reference such as 1.1, optionally narrowed to a subreference such as @μῆνιν[1]. Index is 0 if the subreference has no index.
+type Position struct {
+	Reference    string
+	Subreference string
+	Index        int
+}
+
+//URN is a parsed CTS URN. Version and Exemplar are empty for notional works, End is empty unless the passage is a range.
+type URN struct {
+	Namespace string
+	Textgroup string
+	Work      string
+	Version   string
+	Exemplar  string
+	Start     Position
+	End       Position
+}
+
+//Parse reads a CTS URN. The passage component may be left out together with its colon.
+func Parse(s string) (URN, error) {
+	var u URN
+	parts := strings.SplitN(s, ":", 5)
+	if len(parts) < 4 || parts[0] != "urn" || parts[1] != "cts" {
+		return u, fmt.Errorf("%q is not a CTS URN", s)
+	}
+	if parts[2] == "" {
+		return u, fmt.Errorf("%q has no namespace", s)
+	}
+	u.Namespace = parts[2]
+	work := strings.Split(parts[3], ".")
+	if len(work) > 4 {
+		return u, fmt.Errorf("%q has more than four work components", s)
+	}
+	for _, v := range work {
+		if v == "" {
+			return u, fmt.Errorf("%q has an empty work component", s)
+		}
+	}
+	for len(work) < 4 {
+		work = append(work, "")
+	}
+	u.Textgroup, u.Work, u.Version, u.Exemplar = work[0], work[1], work[2], work[3]
+	if len(parts) < 5 || parts[4] == "" {
+		return u, nil
+	}
+	if strings.Contains(parts[4], ":") {
+		return u, fmt.Errorf("%q has a colon in its passage", s)
+	}
+	start, end, isRange := splitRange(parts[4])
+	var err error
+	if u.Start, err = parsePosition(start); err != nil {
+		return u, fmt.Errorf("%q: %v", s, err)
+	}
+	if isRange {
+		if u.End, err = parsePosition(end); err != nil {
+			return u, fmt.Errorf("%q: %v", s, err)
+		}
+	}
+	return u, nil
+}
+
+//Valid reports whether s is a CTS URN.
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+//splitRange splits a passage at the hyphen of a range. A hyphen before the first @ always starts the end of the range, as references cannot contain one. After a subreference, the first hyphen followed by a position whose reference has as many levels as the start ends the subreference; other hyphens belong to the subreference.
+func splitRange(passage string) (string, string, bool) {
+	at := strings.Index(passage, "@")
+	if i := strings.Index(passage, "-"); i != -1 && (at == -1 || i < at) {
+		return passage[:i], passage[i+1:], true
+	}
+	if at == -1 {
+		return passage, "", false
+	}
+	depth := strings.Count(passage[:at], ".")
+	for i := at; i < len(passage); i++ {
+		if passage[i] != '-' {
+			continue
+		}
+		end, err := parsePosition(passage[i+1:])
+		if err != nil || strings.Count(end.Reference, ".") != depth {
+			continue
+		}
+		if _, err := parsePosition(passage[:i]); err == nil {
+			return passage[:i], passage[i+1:], true
+		}
+	}
+	return passage, "", false
+}
+
+func parsePosition(s string) (Position, error) {
+	var p Position
+	reference, subreference := s, ""
+	if i := strings.Index(s, "@"); i != -1 {
+		reference, subreference = s[:i], s[i+1:]
+		if subreference == "" {
+			return p, fmt.Errorf("empty subreference in %q", s)
+		}
+	}
+	for _, v := range strings.Split(reference, ".") {
+		if v == "" {
+			return p, fmt.Errorf("empty citation level in %q", s)
+		}
+	}
+	if strings.ContainsAny(reference, "-@[]") || strings.IndexFunc(reference, unicode.IsSpace) != -1 {
+		return p, fmt.Errorf("invalid character in citation reference %q", reference)
+	}
+	p.Reference = reference
+	if i := strings.Index(subreference, "["); i != -1 {
+		if !strings.HasSuffix(subreference, "]") {
+			return p, fmt.Errorf("unterminated subreference index in %q", s)
+		}
+		index, err := strconv.Atoi(subreference[i+1 : len(subreference)-1])
+		if err != nil || index < 1 {
+			return p, fmt.Errorf("invalid subreference index in %q", s)
+		}
+		subreference, p.Index = subreference[:i], index
+	}
+	p.Subreference = subreference
+	return p, nil
+}
+
+func (p Position) String() string {
+	s := p.Reference
+	if p.Subreference != "" {
+		s = s + "@" + p.Subreference
+		if p.Index > 0 {
+			s = s + "[" + strconv.Itoa(p.Index) + "]"
+		}
+	}
+	return s
+}
+
+//WorkComponent returns the dotted work component, e.g. tlg0012.tlg001.perseus-grc2.
+func (u URN) WorkComponent() string {
+	components := []string{u.Textgroup}
+	for _, v := range []string{u.Work, u.Version, u.Exemplar} {
+		if v == "" {
+			break
+		}
+		components = append(components, v)
+	}
+	return strings.Join(components, ".")
+}
+
+//Passage returns the passage component, e.g. 1.1-1.5.
+func (u URN) Passage() string {
+	if u.End.Reference != "" {
+		return u.Start.String() + "-" + u.End.String()
+	}
+	return u.Start.String()
+}
+
+//IsRange reports whether the passage is a range.
+func (u URN) IsRange() bool {
+	return u.End.Reference != ""
+}
+
+//String formats the URN. URNs without a passage are written without the trailing colon, as in the CEX catalog.
+func (u URN) String() string {
+	s := "urn:cts:" + u.Namespace + ":" + u.WorkComponent()
+	if u.Start.Reference != "" {
+		s = s + ":" + u.Passage()
+	}
+	return s
+}
+
+//Text returns the URN without its passage.
+func (u URN) Text() URN {
+	return URN{Namespace: u.Namespace, Textgroup: u.Textgroup, Work: u.Work, Version: u.Version, Exemplar: u.Exemplar}
+}
+
+//WithPassage returns the URN of the passage reference of the same text.
+func (u URN) WithPassage(reference ...string) URN {
+	t := u.Text()
+	t.Start.Reference = strings.Join(reference, ".")
+	return t
+}
+
+//WithExemplar returns the URN of an exemplar of the version, e.g. the tokenized exemplar tokens.
+func (u URN) WithExemplar(exemplar string) URN {
+	t := u
+	t.Exemplar = exemplar
+	return t
+}
@@ -0,0 +1,110 @@
+package ctsurn
+
+import "testing"
+
+const iliad = "urn:cts:greekLit:tlg0012.tlg001.perseus-grc2"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		urn   string
+		start Position
+		end   Position
+	}{
+		{iliad, Position{}, Position{}},
+		{iliad + ":", Position{}, Position{}},
+		{iliad + ":1.1", Position{Reference: "1.1"}, Position{}},
+		{iliad + ":1.1-1.5", Position{Reference: "1.1"}, Position{Reference: "1.5"}},
+		{iliad + ":1-2.5", Position{Reference: "1"}, Position{Reference: "2.5"}},
+		{iliad + ":1.1@μῆνιν", Position{Reference: "1.1", Subreference: "μῆνιν"}, Position{}},
+		{iliad + ":1.1@μῆνιν[2]", Position{Reference: "1.1", Subreference: "μῆνιν", Index: 2}, Position{}},
+		{iliad + ":1.1@μῆνιν-1.5", Position{Reference: "1.1", Subreference: "μῆνιν"}, Position{Reference: "1.5"}},
+		{iliad + ":1.1@μῆνιν[1]-1.5@θεὰ", Position{Reference: "1.1", Subreference: "μῆνιν", Index: 1}, Position{Reference: "1.5", Subreference: "θεὰ"}},
+		{iliad + ":1.1-1.2@a-b", Position{Reference: "1.1"}, Position{Reference: "1.2", Subreference: "a-b"}},
+		{iliad + ":1.1@a-b-1.2@c", Position{Reference: "1.1", Subreference: "a-b"}, Position{Reference: "1.2", Subreference: "c"}},
+		{iliad + ":1.1@a-b", Position{Reference: "1.1", Subreference: "a-b"}, Position{}},
+	}
+	for _, test := range tests {
+		u, err := Parse(test.urn)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.urn, err)
+			continue
+		}
+		if u.Start != test.start || u.End != test.end {
+			t.Errorf("Parse(%q) = %+v - %+v, want %+v - %+v", test.urn, u.Start, u.End, test.start, test.end)
+		}
+		if u.IsRange() != (test.end.Reference != "") {
+			t.Errorf("Parse(%q).IsRange() = %v", test.urn, u.IsRange())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"urn:cite2:teitocex:notes.v1:1",
+		"urn:cts::tlg0012.tlg001",
+		"urn:cts:greekLit",
+		"urn:cts:greekLit:tlg0012..perseus-grc2:1.1",
+		"urn:cts:greekLit:tlg0012.tlg001.perseus-grc2.tokens.x:1.1",
+		iliad + ":1.1:2",
+		iliad + ":1..1",
+		iliad + ":1.1-",
+		iliad + ":1.1@",
+		iliad + ":1.1@μῆνιν[0]",
+		iliad + ":1.1@μῆνιν[1",
+		iliad + ":1 1",
+		iliad + ":1.1-1.2-1.3",
+		iliad + ":1.1[1]",
+	}
+	for _, v := range tests {
+		if Valid(v) {
+			t.Errorf("Valid(%q) = true, want false", v)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []string{
+		iliad,
+		iliad + ":1.1",
+		iliad + ":1.1-1.5",
+		iliad + ":1.1@μῆνιν[1]-1.5@θεὰ",
+		"urn:cts:greekLit:tlg0012.tlg001",
+	}
+	for _, v := range tests {
+		u, err := Parse(v)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", v, err)
+			continue
+		}
+		if u.String() != v {
+			t.Errorf("Parse(%q).String() = %q", v, u.String())
+		}
+	}
+	if got := mustParse(t, iliad+":").String(); got != iliad {
+		t.Errorf("trailing colon is kept: %q", got)
+	}
+}
+
+func TestWithPassage(t *testing.T) {
+	u := mustParse(t, iliad+":1.1-1.5")
+	if got := u.WithPassage("2", "3").String(); got != iliad+":2.3" {
+		t.Errorf("WithPassage = %q", got)
+	}
+	if got := u.WithExemplar("tokens").Text().String(); got != iliad+".tokens" {
+		t.Errorf("WithExemplar = %q", got)
+	}
+	if got := u.WorkComponent(); got != "tlg0012.tlg001.perseus-grc2" {
+		t.Errorf("WorkComponent = %q", got)
+	}
+}
+
+//mustParse parses a URN that the test expects to be valid.
+func mustParse(t *testing.T, s string) URN {
+	t.Helper()
+	u, err := Parse(s)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return u
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const defaultDelimiter = "#"
+
+//delimiterCandidates are tried in this order when the delimiter occurs in a value. CEX lets every file choose its delimiter, so texts are never changed to make room for it.
+var delimiterCandidates = []string{"#", "|", "~", "^", "¦", "\t"}
+
+//delimiter returns the delimiter set with -Delimiter, or # by default. If one of the values to be written contains it, the first candidate that occurs in no value is used instead.
+func (o Options) delimiter(values ...[]string) string {
+	preferred := o.Delimiter
+	if preferred == "" {
+		preferred = defaultDelimiter
+	}
+	if !occursIn(preferred, values) {
+		return preferred
+	}
+	for _, v := range delimiterCandidates {
+		if !occursIn(v, values) {
+			fmt.Printf("The delimiter %q occurs in the text, using %q instead.\n", preferred, v)
+			return v
+		}
+	}
+	check(fmt.Errorf("the delimiter %q occurs in the text and so do all of %q, set another one with -Delimiter", preferred, delimiterCandidates))
+	return preferred
+}
+
+func occursIn(delimiter string, values [][]string) bool {
+	for _, list := range values {
+		for _, v := range list {
+			if strings.Contains(v, delimiter) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//cexValues returns every value writeCEX writes between delimiters.
+func cexValues(citelibrary CiteLibrary, ctscatalog CTSCatalog, identifiers, texts []string, collections []CiteCollection, relations CiteRelations, datamodels []CiteDataModel) [][]string {
+	values := [][]string{
+		{citelibrary.Name, citelibrary.URN, citelibrary.License},
+		ctscatalog.URN, ctscatalog.CitationScheme, ctscatalog.GroupName, ctscatalog.WorkTitle, ctscatalog.VersionLabel, ctscatalog.ExemplarLabel, ctscatalog.Online, ctscatalog.Language,
+		identifiers, texts,
+		relations.Subject, relations.Verb, relations.Object,
+	}
+	for _, c := range collections {
+		values = append(values, c.values()...)
+	}
+	for _, v := range datamodels {
+		values = append(values, []string{v.Collection, v.Model, v.Label, v.Description})
+	}
+	return values
+}
+
+//values returns every value of the collection that is written between delimiters.
+func (c CiteCollection) values() [][]string {
+	values := [][]string{{c.URN, c.Description, c.propertyURN(c.Labelling), c.propertyURN(c.Ordering), c.License}}
+	for _, p := range c.Properties {
+		values = append(values, []string{c.propertyURN(p.Name), p.Name, p.Label, p.Type, p.Authority})
+	}
+	return append(values, c.Rows...)
+}
+
+//cexDelimiter finds the delimiter of a CEX file in the header of its #!ctscatalog block, between urn and citationScheme, or else in the header of its #!citecollections block, between URN and Description. Files without either are read with #.
+func cexDelimiter(lines []string) string {
+	headers := map[string][2]string{"#!ctscatalog": {"urn", "citationScheme"}, "#!citecollections": {"URN", "Description"}}
+	found := make(map[string]string)
+	block := ""
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#!") {
+			block = strings.TrimSpace(line)
+			continue
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		header, ok := headers[block]
+		if ok && found[block] == "" && strings.HasPrefix(line, header[0]) {
+			if end := strings.Index(line, header[1]); end > len(header[0]) {
+				found[block] = line[len(header[0]):end]
+			}
+		}
+		block = ""
+	}
+	if v := found["#!ctscatalog"]; v != "" {
+		return v
+	}
+	if v := found["#!citecollections"]; v != "" {
+		return v
+	}
+	return defaultDelimiter
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+)
+
+const entitiesCollectionURN = citeNamespace + "entities.v1:"
+const mentionsVerb = citeNamespace + "verbs.v1:mentions"
+
+//entityElements are the TEI elements collected as named entities.
+var entityElements = map[string]bool{"persName": true, "placeName": true, "rs": true, "date": true}
+
+//EntityCollection container for the named entities of the corpus
+type EntityCollection struct {
+	URN     []string `json:"urn"`
+	Passage []string `json:"passage"`
+	Element []string `json:"element"`
+	Surface []string `json:"surface"`
+	Ref     []string `json:"ref"`
+	Key     []string `json:"key"`
+	Type    []string `json:"type"`
+	When    []string `json:"when"`
+}
+
+//collect adds the named entities of a passage. Elements dropped by the element policy are skipped, so that the index matches the reading text.
+func (c *EntityCollection) collect(passage string, fragment XMLFragment, policy ElementPolicy, breaks map[string]bool) {
+	if fragment.Root == nil {
+		return
+	}
+	// nested entities, e.g. a placeName inside a persName, are indexed as well
+	walkOutermost(fragment.Root, func(node *XMLNode) bool {
+		if policy.dropped(node) {
+			return true
+		}
+		if !entityElements[node.Name] {
+			return false
+		}
+		c.URN = append(c.URN, entitiesCollectionURN+strconv.Itoa(len(c.URN)+1))
+		c.Passage = append(c.Passage, passage)
+		c.Element = append(c.Element, node.Name)
+		c.Surface = append(c.Surface, extractText(fragment.policyXML(node, policy), breaks))
+		c.Ref = append(c.Ref, node.Attrs["ref"])
+		c.Key = append(c.Key, node.Attrs["key"])
+		c.Type = append(c.Type, node.Attrs["type"])
+		c.When = append(c.When, node.Attrs["when"])
+		return false
+	})
+}
+
+func (c EntityCollection) citeCollection() CiteCollection {
+	collection := CiteCollection{
+		URN:         entitiesCollectionURN,
+		Description: "Named entities of the TEI editions",
+		Labelling:   "surface",
+		License:     defaultLicense,
+		Model:       CiteDataModel{Model: citeNamespace + "datamodels.v1:entities", Label: "Named entities", Description: "Named entities linked to the passages that mention them by " + mentionsVerb},
+		Properties: []CiteProperty{
+			{Name: "urn", Label: "Entity", Type: "Cite2Urn"},
+			{Name: "passage", Label: "Passage", Type: "CtsUrn"},
+			{Name: "element", Label: "Element", Type: "String"},
+			{Name: "surface", Label: "Surface form", Type: "String"},
+			{Name: "ref", Label: "Reference", Type: "String"},
+			{Name: "key", Label: "Key", Type: "String"},
+			{Name: "type", Label: "Type", Type: "String"},
+			{Name: "when", Label: "Date", Type: "String"},
+		},
+	}
+	for i := range c.URN {
+		collection.Rows = append(collection.Rows, []string{c.URN[i], c.Passage[i], c.Element[i], c.Surface[i], c.Ref[i], c.Key[i], c.Type[i], c.When[i]})
+	}
+	return collection
+}
+
+//relations links every passage to the named entities it mentions.
+func (c EntityCollection) relations(relations *CiteRelations) {
+	for i := range c.URN {
+		relations.add(c.Passage[i], mentionsVerb, c.URN[i])
+	}
+}
+
+func writeEntitiesJSON(outputFile string, entities EntityCollection) {
+	jsonentities, err1 := json.Marshal(entities)
+	check(err1)
+	f, err2 := os.Create(outputFile)
+	check(err2)
+	defer f.Close()
+	_, err := f.WriteString(string(jsonentities))
+	check(err)
+}
@@ -2,4 +2,7 @@ module github.com/ThomasK81/TEItoCEX
 
 go 1.14
 
-require github.com/mattn/go-sqlite3 v1.14.0
+require (
+	github.com/mattn/go-sqlite3 v1.14.0
+	golang.org/x/text v0.3.3
+)
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+)
+
+//nodeLanguage returns the language in effect at node, from the xml:lang of the node or its nearest ancestor. TEI P4 files use lang instead.
+func nodeLanguage(node *XMLNode) string {
+	for n := node; n != nil; n = n.Parent {
+		if lang := strings.TrimSpace(firstNonEmpty(n.Attrs["xml:lang"], n.Attrs["lang"])); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+//LanguageCodes maps the language ids of a header to ISO 639 codes, e.g. greek to grc.
+type LanguageCodes map[string]string
+
+//p4LanguageCodes are the ISO 639 codes of the language ids and names used by TEI P4 files.
+var p4LanguageCodes = map[string]string{
+	"greek": "grc", "latin": "lat", "english": "eng", "german": "deu", "french": "fra", "italian": "ita", "spanish": "spa",
+	"arabic": "ara", "hebrew": "heb", "syriac": "syc", "coptic": "cop", "armenian": "hye", "ethiopic": "gez", "sanskrit": "san",
+}
+
+//headerLanguages returns the codes of the langUsage of a header, in order, and the map from their ids to the codes. A P4 language without ident is given the code of its id or name, e.g. <language id="greek">Greek</language> becomes grc.
+func headerLanguages(languages []LangInfo) (LanguageCodes, []string) {
+	codes := make(LanguageCodes)
+	var list []string
+	for _, v := range languages {
+		code := strings.TrimSpace(v.Language)
+		if code == "" {
+			code = firstNonEmpty(p4LanguageCodes[strings.ToLower(strings.TrimSpace(v.P4ID))], p4LanguageCodes[strings.ToLower(collapseWhitespace(v.Name))], strings.TrimSpace(v.P4ID))
+		}
+		if code == "" {
+			continue
+		}
+		if id := strings.TrimSpace(v.P4ID); id != "" {
+			codes[id] = code
+		}
+		codes[code] = code
+		list = append(list, code)
+	}
+	return codes, list
+}
+
+//code returns the ISO code of a language id, or the id itself if it is not known.
+func (c LanguageCodes) code(lang string) string {
+	if code, ok := c[lang]; ok {
+		return code
+	}
+	return firstNonEmpty(p4LanguageCodes[strings.ToLower(lang)], lang)
+}
+
+//passageLanguages returns the language of a passage followed by the other languages that are set with xml:lang inside it, e.g. grc,lat for Greek with a Latin foreign.
+func passageLanguages(cited CitedNode, fragment XMLFragment, fallback string, codes LanguageCodes) string {
+	languages := []string{firstNonEmpty(codes.code(nodeLanguage(cited.Node)), fallback)}
+	if fragment.Root == nil {
+		return languages[0]
+	}
+	seen := map[string]bool{languages[0]: true}
+	walkOutermost(fragment.Root, func(node *XMLNode) bool {
+		lang := codes.code(strings.TrimSpace(firstNonEmpty(node.Attrs["xml:lang"], node.Attrs["lang"])))
+		if lang != "" && !seen[lang] {
+			seen[lang] = true
+			languages = append(languages, lang)
+		}
+		return false
+	})
+	return strings.Join(languages, ",")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var tagNameRegExp = regexp.MustCompile(`^<([^\s/>]+)`)
+
+//MilestoneSegment is the XML between two milestones of the chosen unit.
+type MilestoneSegment struct {
+	N        string
+	InnerXML string
+}
+
+//isMilestone reports whether an element starts a new segment of unit: a milestone with that unit, or an element named like the unit, e.g. lb or pb.
+func isMilestone(se xml.StartElement, unit string) (string, bool) {
+	if se.Name.Local != unit && !(se.Name.Local == "milestone" && attrValue(se, "unit") == unit) {
+		return "", false
+	}
+	n := strings.TrimSpace(attrValue(se, "n"))
+	return n, n != ""
+}
+
+func attrValue(se xml.StartElement, name string) string {
+	for _, attr := range se.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+//splitAtMilestones cuts inner XML at every milestone of unit. An empty milestone is left out of the segments, an element named like the unit that has content (e.g. l) starts a segment with its start tag. Elements that are open at a cut are closed and reopened, so that every segment is well-formed. Text before the first milestone continues the segment of the previous milestone last; if there is none, it is numbered 0.
+func splitAtMilestones(inner string, unit string, last string) ([]MilestoneSegment, string, error) {
+	var segments []MilestoneSegment
+	var open []string
+	current := last
+	if current == "" {
+		current = "0"
+	}
+	reopen := ""
+	segStart := int64(0)
+	emit := func(end int64) {
+		fragment := reopen + inner[segStart:end]
+		for i := len(open) - 1; i >= 0; i-- {
+			fragment = fragment + "</" + tagNameRegExp.FindStringSubmatch(open[i])[1] + ">"
+		}
+		if stringcleaning(fragment) != "" {
+			segments = append(segments, MilestoneSegment{N: current, InnerXML: strings.TrimSpace(fragment)})
+		}
+	}
+	decoder := newLenientDecoder([]byte(inner))
+	for {
+		before := decoder.InputOffset()
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, last, err
+		}
+		switch se := t.(type) {
+		case xml.StartElement:
+			tag := inner[before:decoder.InputOffset()]
+			if n, ok := isMilestone(se, unit); ok {
+				emit(before)
+				reopen = strings.Join(open, "")
+				segStart = before
+				current = n
+				last = n
+				if strings.HasSuffix(tag, "/>") {
+					// skip the synthetic end element of the empty milestone
+					if _, err := decoder.Token(); err != nil {
+						return nil, last, err
+					}
+					segStart = decoder.InputOffset()
+					continue
+				}
+			}
+			open = append(open, tag)
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	open = nil
+	emit(int64(len(inner)))
+	return segments, last, nil
+}
+
+//splitPassagesAtMilestones turns every passage into a container of the segments between the milestones of unit. The n of the milestone is added as citation component.
+func splitPassagesAtMilestones(passages []CitedNode, document []byte, unit string) ([]CitedNode, error) {
+	var result []CitedNode
+	last := ""
+	for _, cited := range passages {
+		if !cited.Leaf {
+			result = append(result, cited)
+			continue
+		}
+		segments, n, err := splitAtMilestones(cited.innerXML(document), unit, last)
+		if err != nil {
+			return nil, err
+		}
+		last = n
+		cited.Leaf = false
+		result = append(result, cited)
+		for _, v := range segments {
+			reference := append(append([]string{}, cited.Reference...), v.N)
+			result = append(result, CitedNode{Node: cited.Node, Reference: reference, Label: unit, Leaf: true, Fragment: v.InnerXML})
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitAtMilestones(t *testing.T) {
+	tests := []struct {
+		name     string
+		inner    string
+		unit     string
+		last     string
+		segments []MilestoneSegment
+		newLast  string
+	}{
+		{"open elements are closed and reopened", `a <p>b <hi>c<milestone unit="section" n="2"/>d</hi> e</p> f`, "section", "1",
+			[]MilestoneSegment{{"1", "a <p>b <hi>c</hi></p>"}, {"2", "<p><hi>d</hi> e</p> f"}}, "2"},
+		{"text before the first milestone is numbered 0", `a<milestone unit="section" n="1"/>b`, "section", "",
+			[]MilestoneSegment{{"0", "a"}, {"1", "b"}}, "1"},
+		{"other units and empty n are ignored", `a<milestone unit="card" n="5"/>b<milestone unit="section" n=""/>c<milestone unit="section" n="3"/>d`, "section", "",
+			[]MilestoneSegment{{"0", `a<milestone unit="card" n="5"/>b<milestone unit="section" n=""/>c`}, {"3", "d"}}, "3"},
+		{"empty unit elements", `one<lb n="1"/>two<lb n="2"/>three`, "lb", "",
+			[]MilestoneSegment{{"0", "one"}, {"1", "two"}, {"2", "three"}}, "2"},
+		{"unit elements with content keep their tags", `<l n="1">one</l><l n="2">two <hi>x</hi></l>`, "l", "",
+			[]MilestoneSegment{{"1", `<l n="1">one</l>`}, {"2", `<l n="2">two <hi>x</hi></l>`}}, "2"},
+		{"segments without text are left out", `<milestone unit="section" n="1"/> <milestone unit="section" n="2"/>b`, "section", "",
+			[]MilestoneSegment{{"2", "b"}}, "2"},
+		{"no milestone", `text only`, "section", "",
+			[]MilestoneSegment{{"0", "text only"}}, ""},
+	}
+	for _, test := range tests {
+		segments, last, err := splitAtMilestones(test.inner, test.unit, test.last)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(segments, test.segments) || last != test.newLast {
+			t.Errorf("%s: got %q and last %q, want %q and last %q", test.name, segments, last, test.segments, test.newLast)
+		}
+	}
+}
+
+func TestSplitPassagesAtMilestones(t *testing.T) {
+	document := []byte(`<body><div n="1"><p>a <milestone unit="section" n="1"/>b <milestone unit="section" n="2"/>c</p></div><div n="2"><p>d <milestone unit="section" n="3"/>e</p></div></body>`)
+	root, err := parseXMLTree(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var passages []CitedNode
+	for _, div := range childrenNamed(root, "div") {
+		passages = append(passages, CitedNode{Node: div, Reference: []string{div.Attrs["n"]}, Leaf: true})
+	}
+	split, err := splitPassagesAtMilestones(passages, document, "section")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d continues section 2, which starts in the first div
+	want := []string{"1", "1.0*", "1.1*", "1.2*", "2", "2.2*", "2.3*"}
+	if got := citedReferences(split); !reflect.DeepEqual(got, want) {
+		t.Errorf("split into %q, want %q", got, want)
+	}
+	if _, err := splitPassagesAtMilestones([]CitedNode{{Node: root, Leaf: true, Fragment: "<p>broken</q>"}}, document, "section"); err == nil {
+		t.Errorf("a passage that is not well-formed is split without an error")
+	}
+}
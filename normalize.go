@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+//normalizationForms are the values accepted by -Normalize.
+var normalizationForms = map[string]norm.Form{"NFC": norm.NFC, "NFD": norm.NFD}
+
+//oxiaRunes are the Greek letters with oxia, which are canonically equivalent to the letters with tonos.
+const oxiaRunes = "\u1f71\u1f73\u1f75\u1f77\u1f79\u1f7b\u1f7d\u1fbb\u1fc9\u1fcb\u1fdb\u1ff9\u1feb\u1ffb\u1fd3\u1fe3"
+
+//tonosReplacer replaces every letter with oxia by its letter with tonos.
+var tonosReplacer = func() *strings.Replacer {
+	var pairs []string
+	for _, r := range oxiaRunes {
+		pairs = append(pairs, string(r), norm.NFC.String(string(r)))
+	}
+	return strings.NewReplacer(pairs...)
+}()
+
+//normalizeDocument applies the -Normalize and -Tonos options to a whole document before it is parsed, so that all outputs get the same text.
+func (o Options) normalizeDocument(document []byte) []byte {
+	return []byte(o.normalizeText(string(document)))
+}
+
+//normalizeText applies the -Normalize and -Tonos options to text, e.g. to the passages read with -Input.
+func (o Options) normalizeText(text string) string {
+	if o.Tonos {
+		text = tonosReplacer.Replace(text)
+	}
+	if form, ok := normalizationForms[o.Normalize]; ok {
+		text = form.String(text)
+	}
+	return text
+}
+
+//normalizeCatalog applies the -Normalize and -Tonos options to the labels of the catalog, which also come from __cts__.xml files or the CEX input, so that they match the passages.
+func (o Options) normalizeCatalog(ctscatalog CTSCatalog) CTSCatalog {
+	for _, column := range []*[]string{&ctscatalog.GroupName, &ctscatalog.WorkTitle, &ctscatalog.VersionLabel, &ctscatalog.ExemplarLabel, &ctscatalog.Description} {
+		for i, v := range *column {
+			(*column)[i] = o.normalizeText(v)
+		}
+	}
+	return ctscatalog
+}
+
+//searchText strips accents, breathings and all other combining marks from text, e.g. for a search index.
+func searchText(text string) string {
+	decomposed := norm.NFD.String(text)
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+	return norm.NFC.String(stripped)
+}
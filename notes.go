@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strconv"
+)
+
+const notesCollectionURN = citeNamespace + "notes.v1:"
+const commentsOnVerb = "urn:cite2:cite:verbs.v1:commentsOn"
+
+//NoteCollection container for the tei:note elements of the corpus
+type NoteCollection struct {
+	URN     []string `json:"urn"`
+	Passage []string `json:"passage"`
+	N       []string `json:"n"`
+	Type    []string `json:"type"`
+	Place   []string `json:"place"`
+	Text    []string `json:"text"`
+}
+
+//collect adds the notes of a passage, read from its XML before the element policy is applied.
+func (c *NoteCollection) collect(passage string, fragment XMLFragment, breaks map[string]bool) {
+	if fragment.Root == nil {
+		return
+	}
+	walkOutermost(fragment.Root, func(node *XMLNode) bool {
+		if node.Name != "note" {
+			return false
+		}
+		c.URN = append(c.URN, notesCollectionURN+strconv.Itoa(len(c.URN)+1))
+		c.Passage = append(c.Passage, passage)
+		c.N = append(c.N, node.Attrs["n"])
+		c.Type = append(c.Type, node.Attrs["type"])
+		c.Place = append(c.Place, node.Attrs["place"])
+		c.Text = append(c.Text, extractText(node.InnerXML(fragment.Document), breaks))
+		return true
+	})
+}
+
+func (c NoteCollection) citeCollection() CiteCollection {
+	collection := CiteCollection{
+		URN:         notesCollectionURN,
+		Description: "Notes of the TEI editions",
+		Labelling:   "text",
+		License:     defaultLicense,
+		Model:       CiteDataModel{Model: commentaryModel, Label: "Commentary", Description: "Notes linked to the passages they comment on by " + commentsOnVerb},
+		Properties: []CiteProperty{
+			{Name: "urn", Label: "Note", Type: "Cite2Urn"},
+			{Name: "passage", Label: "Passage", Type: "CtsUrn"},
+			{Name: "n", Label: "Number", Type: "String"},
+			{Name: "type", Label: "Type", Type: "String"},
+			{Name: "place", Label: "Place", Type: "String"},
+			{Name: "text", Label: "Text", Type: "String"},
+		},
+	}
+	for i := range c.URN {
+		collection.Rows = append(collection.Rows, []string{c.URN[i], c.Passage[i], c.N[i], c.Type[i], c.Place[i], c.Text[i]})
+	}
+	return collection
+}
+
+//relations links every note to the passage it comments on.
+func (c NoteCollection) relations(relations *CiteRelations) {
+	for i := range c.URN {
+		relations.add(c.URN[i], commentsOnVerb, c.Passage[i])
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode"
+)
+
+const usage = "Usage: CTSExtract [output-filename] [optionally: -CSV|JSON|XML|SQL|HTML|Markdown|Cat|Tree] [options: -Config=file.json -P4 -Milestone=unit -Drop=elements -Keep=elements -Prefer=element:alternative -Breaks=elements -Scripts=Greek,Latin,Hebrew -Tokens -Enclitics[=all] -Normalize=NFC|NFD -Tonos -Search -Input=file.cex -LibraryName=name -LibraryURN=urn -License=license -Delimiter=character -Split=textgroup|work]"
+
+var outputModes = []string{"-CSV", "-JSON", "-XML", "-SQL", "-HTML", "-Markdown", "-Cat", "-Tree"}
+
+//Options container for the command line switches that modify the extraction. The same fields can be set in a JSON config file given with -Config.
+type Options struct {
+	P4          bool              `json:"p4"`
+	Milestone   string            `json:"milestone"`
+	Drop        []string          `json:"drop"`
+	Keep        []string          `json:"keep"`
+	Prefer      map[string]string `json:"prefer"`
+	Breaks      []string          `json:"breaks"`
+	Scripts     []string          `json:"scripts"`
+	Tokens      bool              `json:"tokens"`
+	Enclitics   bool              `json:"enclitics"`
+	NeVe        bool              `json:"ne_ve"`
+	Normalize   string            `json:"normalize"`
+	Tonos       bool              `json:"tonos"`
+	Search      bool              `json:"search"`
+	Input       string            `json:"input"`
+	LibraryName string            `json:"library_name"`
+	LibraryURN  string            `json:"library_urn"`
+	License     string            `json:"license"`
+	Delimiter   string            `json:"delimiter"`
+	Split       string            `json:"split"`
+}
+
+//parseArgs splits the arguments following the output file into the output mode and the options. Options are given as -Name or -Name=value, a config file is read first so that the command line takes precedence.
+func parseArgs(args []string) (mode string, options Options, err error) {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-Config=") {
+			byteValue, err := ioutil.ReadFile(strings.TrimPrefix(arg, "-Config="))
+			if err != nil {
+				return mode, options, err
+			}
+			if err = json.Unmarshal(byteValue, &options); err != nil {
+				return mode, options, fmt.Errorf("config file %s: %v", strings.TrimPrefix(arg, "-Config="), err)
+			}
+		}
+	}
+	for _, arg := range args {
+		if isOutputMode(arg) {
+			if mode != "" {
+				return mode, options, fmt.Errorf("more than one output mode given: %s and %s", mode, arg)
+			}
+			mode = arg
+			continue
+		}
+		name, value := arg, ""
+		if i := strings.Index(arg, "="); i != -1 {
+			name, value = arg[:i], arg[i+1:]
+		}
+		switch name {
+		case "-P4":
+			options.P4 = true
+		case "-Milestone":
+			if value == "" {
+				return mode, options, fmt.Errorf("%s needs a unit, e.g. -Milestone=section, -Milestone=lb or -Milestone=pb", name)
+			}
+			options.Milestone = value
+		case "-Drop":
+			options.Drop = append(options.Drop, splitList(value)...)
+		case "-Keep":
+			options.Keep = append(options.Keep, splitList(value)...)
+		case "-Prefer":
+			for _, pair := range splitList(value) {
+				elements := strings.Split(pair, ":")
+				if len(elements) != 2 {
+					return mode, options, fmt.Errorf("%s expects element:alternative pairs, e.g. -Prefer=sic:corr", name)
+				}
+				if options.Prefer == nil {
+					options.Prefer = make(map[string]string)
+				}
+				options.Prefer[elements[0]] = elements[1]
+			}
+		case "-Breaks":
+			options.Breaks = splitList(value)
+		case "-Tokens":
+			options.Tokens = true
+		case "-Enclitics":
+			switch value {
+			case "":
+				options.Enclitics = true
+			case "all":
+				options.Enclitics = true
+				options.NeVe = true
+			default:
+				return mode, options, fmt.Errorf("%s splits -que, -Enclitics=all also -ne and -ve", name)
+			}
+		case "-Normalize":
+			options.Normalize = value
+		case "-Tonos":
+			options.Tonos = true
+		case "-Search":
+			options.Search = true
+		case "-Input":
+			if value == "" {
+				return mode, options, fmt.Errorf("%s needs a CEX file, e.g. -Input=corpus.cex", name)
+			}
+			options.Input = value
+		case "-LibraryName":
+			options.LibraryName = value
+		case "-LibraryURN":
+			options.LibraryURN = value
+		case "-License":
+			options.License = value
+		case "-Delimiter":
+			options.Delimiter = value
+		case "-Split":
+			options.Split = value
+		case "-Scripts":
+			options.Scripts = splitList(value)
+		case "-Config":
+			// already read above
+		default:
+			return mode, options, fmt.Errorf("unknown option %s", arg)
+		}
+	}
+	if _, ok := normalizationForms[options.Normalize]; options.Normalize != "" && !ok {
+		return mode, options, fmt.Errorf("unknown normalization form %s, use NFC or NFD", options.Normalize)
+	}
+	if strings.ContainsAny(options.Delimiter, "\r\n") {
+		return mode, options, fmt.Errorf("the delimiter cannot contain a line break")
+	}
+	if options.Split != "" && !splitUnits[options.Split] {
+		return mode, options, fmt.Errorf("unknown unit %s for -Split, use textgroup or work", options.Split)
+	}
+	if options.Split != "" && mode != "" {
+		return mode, options, fmt.Errorf("-Split only applies to CEX output, not to %s", mode)
+	}
+	if options.Input != "" && (options.P4 || options.Milestone != "" || len(options.Drop) > 0 || len(options.Keep) > 0 || len(options.Prefer) > 0 || len(options.Breaks) > 0) {
+		return mode, options, fmt.Errorf("-P4, -Milestone, -Drop, -Keep, -Prefer and -Breaks only apply to TEI files, not to -Input")
+	}
+	if options.LibraryURN != "" {
+		if err := validCite2URN(options.LibraryURN); err != nil {
+			return mode, options, err
+		}
+	}
+	for _, v := range options.Scripts {
+		if _, ok := unicode.Scripts[v]; !ok {
+			return mode, options, fmt.Errorf("unknown Unicode script %s, e.g. Greek, Latin, Hebrew, Syriac, Coptic, Armenian or Ethiopic", v)
+		}
+	}
+	return mode, options, nil
+}
+
+func isOutputMode(arg string) bool {
+	for _, v := range outputModes {
+		if v == arg {
+			return true
+		}
+	}
+	return false
+}
+
+//splitList splits a comma separated option value.
+func splitList(value string) []string {
+	var result []string
+	for _, v := range strings.Split(value, ",") {
+		if strings.TrimSpace(v) != "" {
+			result = append(result, strings.TrimSpace(v))
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var numberedDivRegExp = regexp.MustCompile(`^div[1-7]?$`)
+
+//p4Units returns the citation units of the first refsDecl that declares them with TEI P4 state or step elements.
+func p4Units(root *XMLNode) []string {
+	for _, header := range childrenNamed(root, "teiHeader") {
+		for _, encoding := range childrenNamed(header, "encodingDesc") {
+			for _, decl := range childrenNamed(encoding, "refsDecl") {
+				units := []string{}
+				for _, state := range childrenNamed(decl, "state") {
+					units = append(units, state.Attrs["unit"])
+				}
+				if len(units) == 0 {
+					for _, step := range childrenNamed(decl, "step") {
+						units = append(units, step.Attrs["refunit"])
+					}
+				}
+				if len(units) > 0 {
+					return units
+				}
+			}
+		}
+	}
+	return nil
+}
+
+//resolveNumberedDivs cites a TEI P4 (or P5) document by its numbered divisions: every div, div1 ... div7 with an n attribute adds a citation level, divisions without n and version divs are transparent. Texts inside text>group>text are numbered by their n attribute or their position; a text without numbered divisions is a passage of its own. Units label the levels, otherwise the type of the division is used. A non-nil scope restricts the resolution to that version div. Text of a division outside its numbered divisions, e.g. a line before its first div2, is cited as the division's passage 0. The references of divisions whose text cannot be cited that way, because they already have a division numbered 0, are returned as well.
+func resolveNumberedDivs(root *XMLNode, document []byte, units []string, scope *XMLNode) ([]CitedNode, []string) {
+	r := divisionResolver{document: document, units: units}
+	if scope != nil {
+		r.resolveDivisions(scope, nil)
+		return r.result, r.uncited
+	}
+	for _, text := range childrenNamed(root, "text") {
+		r.resolveText(text, nil)
+	}
+	return r.result, r.uncited
+}
+
+//divisionResolver collects the cited divisions of a document.
+type divisionResolver struct {
+	document []byte
+	units    []string
+	result   []CitedNode
+	uncited  []string
+}
+
+func (r *divisionResolver) resolveText(text *XMLNode, reference []string) {
+	for _, child := range text.Children {
+		switch child.Name {
+		case "body":
+			r.resolveDivisions(child, reference)
+		case "group":
+			for i, inner := range childrenNamed(child, "text") {
+				n, ok := inner.Attr("n")
+				if !ok {
+					n = strconv.Itoa(i + 1)
+				}
+				innerReference := append(append([]string{}, reference...), n)
+				index := len(r.result)
+				r.result = append(r.result, CitedNode{Node: inner, Reference: innerReference, Label: unitLabel(r.units, len(innerReference), "text")})
+				r.resolveText(inner, innerReference)
+				if len(r.result) == index+1 {
+					// a text without numbered divisions is cited as a whole
+					r.result[index].Leaf = true
+				}
+			}
+		}
+	}
+}
+
+//resolveDivisions adds the numbered divisions below node and reports whether it found any.
+func (r *divisionResolver) resolveDivisions(node *XMLNode, reference []string) bool {
+	found := false
+	for _, child := range node.Children {
+		if !numberedDivRegExp.MatchString(child.Name) {
+			continue
+		}
+		n, ok := child.Attr("n")
+		if !ok || isVersionDiv(child) {
+			if r.resolveDivisions(child, reference) {
+				found = true
+			}
+			continue
+		}
+		found = true
+		childReference := append(append([]string{}, reference...), n)
+		label, _ := child.Attr("type")
+		cited := CitedNode{Node: child, Reference: childReference, Label: unitLabel(r.units, len(childReference), label)}
+		index := len(r.result)
+		r.result = append(r.result, cited)
+		if !r.resolveDivisions(child, childReference) {
+			r.result[index].Leaf = true
+			continue
+		}
+		r.citeRemainder(index)
+	}
+	return found
+}
+
+//citeRemainder inserts the text of the container at index that lies outside its cited divisions as a passage numbered 0 right after the container.
+func (r *divisionResolver) citeRemainder(index int) {
+	container := r.result[index]
+	depth := len(container.Reference) + 1
+	var parts []string
+	label := ""
+	taken := false
+	start := container.Node.InnerStart
+	for _, v := range r.result[index+1:] {
+		if len(v.Reference) != depth {
+			continue
+		}
+		if label == "" {
+			label = v.Label
+		}
+		taken = taken || v.Reference[depth-1] == "0"
+		parts = append(parts, string(r.document[start:v.Node.OuterStart]))
+		start = v.Node.OuterEnd
+	}
+	parts = append(parts, string(r.document[start:container.Node.InnerEnd]))
+	remainder := strings.TrimSpace(strings.Join(parts, "\n"))
+	if stringcleaning(remainder) == "" {
+		return
+	}
+	if taken {
+		r.uncited = append(r.uncited, strings.Join(container.Reference, "."))
+		return
+	}
+	reference := append(append([]string{}, container.Reference...), "0")
+	cited := CitedNode{Node: container.Node, Reference: reference, Label: label, Leaf: true, Fragment: remainder}
+	r.result = append(r.result[:index+1], append([]CitedNode{cited}, r.result[index+1:]...)...)
+}
+
+//milestoneUnits returns the declared units below the depth of the cited divisions. Perseus P4 files mark them with milestones rather than divisions, e.g. <state unit="section"/> with <milestone unit="section"/>. Units that node marks with a milestone or an element of that name are returned to be split at, the others as missing.
+func milestoneUnits(node *XMLNode, units []string, depth int) (split []string, missing []string) {
+	for i := depth; i < len(units); i++ {
+		if units[i] == "" {
+			continue
+		}
+		if hasMilestone(node, units[i]) {
+			split = append(split, units[i])
+		} else {
+			missing = append(missing, units[i])
+		}
+	}
+	return split, missing
+}
+
+func hasMilestone(node *XMLNode, unit string) bool {
+	if node.Name == unit || node.Name == "milestone" && node.Attrs["unit"] == unit {
+		return true
+	}
+	for _, child := range node.Children {
+		if hasMilestone(child, unit) {
+			return true
+		}
+	}
+	return false
+}
+
+//isVersionDiv reports whether a division holds a version, e.g. div[@type='edition'] or a div with a CTS URN as n, rather than a citable part of it.
+func isVersionDiv(node *XMLNode) bool {
+	return node.Name == "div" && versionTypes[node.Attrs["type"]] || strings.HasPrefix(node.Attrs["n"], "urn:cts:")
+}
+
+func unitLabel(units []string, depth int, fallback string) string {
+	if depth <= len(units) && units[depth-1] != "" {
+		return units[depth-1]
+	}
+	if fallback == "" {
+		return "section"
+	}
+	return fallback
+}
+
+//numberedDivScheme returns the labels along the path to the first leaf, which serve as citation scheme.
+func numberedDivScheme(passages []CitedNode) []string {
+	labels := []string{}
+	for _, v := range passages {
+		labels = append(labels[:len(v.Reference)-1], v.Label)
+		if v.Leaf {
+			return labels
+		}
+	}
+	return nil
+}
+
+func childrenNamed(node *XMLNode, name string) []*XMLNode {
+	var result []*XMLNode
+	for _, child := range node.Children {
+		if child.Name == name {
+			result = append(result, child)
+		}
+	}
+	return result
+}
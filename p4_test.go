@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveNumberedDivs(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		units   []string
+		want    []string
+		labels  []string
+		texts   map[string]string
+		uncited []string
+	}{
+		{"levels at any depth", `<TEI.2><text><body><div1 type="book" n="1"><div2 type="chapter" n="1"><p>a</p></div2><div2 type="chapter" n="2"><p>b</p></div2></div1><div1 type="book" n="2"><p>c</p></div1></body></text></TEI.2>`,
+			nil, []string{"1", "1.1*", "1.2*", "2*"}, []string{"book", "chapter", "chapter", "book"}, nil, nil},
+		{"units name the levels", `<TEI.2><text><body><div1 type="x" n="1"><div2 n="1"><p>a</p></div2></div1></body></text></TEI.2>`,
+			[]string{"book", "section"}, []string{"1", "1.1*"}, []string{"book", "section"}, nil, nil},
+		{"divisions without n and version divs are transparent", `<TEI.2><text><body><div type="edition" n="urn:cts:greekLit:tlg1.tlg1.x"><div1 n="1"><div><div2 n="1"><p>a</p></div2></div></div1></div></body></text></TEI.2>`,
+			nil, []string{"1", "1.1*"}, []string{"section", "section"}, nil, nil},
+		{"text outside the divisions is passage 0", `<TEI.2><text><body><div1 n="1"><head>Book</head><l>one two</l><div2 n="x"><l>three</l></div2><l>four</l><div><div2 n="y"><l>five</l></div2></div></div1></body></text></TEI.2>`,
+			nil, []string{"1", "1.0*", "1.x*", "1.y*"}, nil, map[string]string{"1.0": "Book one two four", "1.x": "three", "1.y": "five"}, nil},
+		{"passage 0 that exists already", `<TEI.2><text><body><div1 n="1"><l>lost</l><div2 n="0"><l>zero</l></div2></div1></body></text></TEI.2>`,
+			nil, []string{"1", "1.0*"}, nil, map[string]string{"1.0": "zero"}, []string{"1"}},
+		{"texts of a group", `<TEI.2><text><group><text n="1"><body><l>first hymn line</l></body></text><text><body><div1 n="a"><l>second</l></div1></body></text></group></text></TEI.2>`,
+			nil, []string{"1*", "2", "2.a*"}, []string{"text", "text", "section"}, nil, nil},
+	}
+	for _, test := range tests {
+		document := []byte(test.xml)
+		root, err := parseXMLTree(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		passages, uncited := resolveNumberedDivs(root, document, test.units, nil)
+		if got := citedReferences(passages); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: resolved %q, want %q", test.name, got, test.want)
+			continue
+		}
+		if test.labels != nil {
+			var labels []string
+			for _, v := range passages {
+				labels = append(labels, v.Label)
+			}
+			if !reflect.DeepEqual(labels, test.labels) {
+				t.Errorf("%s: labels %q, want %q", test.name, labels, test.labels)
+			}
+		}
+		for _, v := range passages {
+			reference := citedReferences([]CitedNode{v})[0]
+			if want, ok := test.texts[reference[:len(reference)-1]]; ok && v.Leaf {
+				if got := stringcleaning(v.innerXML(document)); got != want {
+					t.Errorf("%s: passage %s has %q, want %q", test.name, reference, got, want)
+				}
+			}
+		}
+		if !reflect.DeepEqual(uncited, test.uncited) {
+			t.Errorf("%s: uncited %q, want %q", test.name, uncited, test.uncited)
+		}
+	}
+}
+
+func TestResolveNumberedDivsScope(t *testing.T) {
+	document := []byte(`<TEI.2><text><body><div type="edition" n="urn:cts:greekLit:tlg1.tlg1.grc"><div1 n="1"><p>a</p></div1><div1 n="2"><p>b</p></div1></div><div type="translation" n="urn:cts:greekLit:tlg1.tlg1.eng"><div1 n="1"><p>A</p></div1></div></body></text></TEI.2>`)
+	root, err := parseXMLTree(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := separateVersions(root)
+	if len(versions) != 2 {
+		t.Fatalf("found %d versions, want 2", len(versions))
+	}
+	passages, _ := resolveNumberedDivs(root, document, nil, versions[1])
+	if got := citedReferences(passages); !reflect.DeepEqual(got, []string{"1*"}) || stringcleaning(passages[0].innerXML(document)) != "A" {
+		t.Errorf("the translation resolves to %q", got)
+	}
+	passages, _ = resolveNumberedDivs(root, document, nil, nil)
+	if got := citedReferences(passages); !reflect.DeepEqual(got, []string{"1*", "2*", "1*"}) {
+		t.Errorf("the whole file resolves to %q", got)
+	}
+}
+
+func TestMilestoneUnits(t *testing.T) {
+	root, err := parseXMLTree([]byte(`<TEI.2><teiHeader><encodingDesc><refsDecl><state unit="book"/><state unit="section"/><state unit="line"/></refsDecl></encodingDesc></teiHeader><text><body><div1 n="1"><p><milestone unit="section" n="1"/>a</p></div1></body></text></TEI.2>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	units := p4Units(root)
+	if !reflect.DeepEqual(units, []string{"book", "section", "line"}) {
+		t.Fatalf("units %q, want book, section, line", units)
+	}
+	split, missing := milestoneUnits(root, units, 1)
+	if !reflect.DeepEqual(split, []string{"section"}) || !reflect.DeepEqual(missing, []string{"line"}) {
+		t.Errorf("split at %q with %q missing, want section with line missing", split, missing)
+	}
+}
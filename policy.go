@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+)
+
+//ElementPolicy decides which TEI elements contribute to the reading text. Dropped elements are removed together with their content. Of a preferred element and its alternative within the same parent (e.g. corr and sic in a choice), only the preferred one is kept. All other elements keep their content.
+type ElementPolicy struct {
+	Drop   map[string]bool
+	Prefer map[string]string
+}
+
+func defaultElementPolicy() ElementPolicy {
+	return ElementPolicy{
+		Drop: map[string]bool{"note": true, "del": true, "speaker": true},
+		Prefer: map[string]string{
+			"corr":  "sic",
+			"lem":   "rdg",
+			"expan": "abbr",
+			"reg":   "orig",
+		},
+	}
+}
+
+//elementPolicy applies the -Drop, -Keep and -Prefer options to the default policy.
+func (o Options) elementPolicy() ElementPolicy {
+	policy := defaultElementPolicy()
+	for _, v := range o.Drop {
+		policy.Drop[v] = true
+	}
+	for preferred, rejected := range o.Prefer {
+		if policy.Prefer[rejected] == preferred {
+			delete(policy.Prefer, rejected)
+		}
+		policy.Prefer[preferred] = rejected
+	}
+	for _, v := range o.Keep {
+		delete(policy.Drop, v)
+		for preferred, rejected := range policy.Prefer {
+			if rejected == v {
+				delete(policy.Prefer, preferred)
+			}
+		}
+	}
+	return policy
+}
+
+//dropped reports whether node and its content are removed from the reading text.
+func (p ElementPolicy) dropped(node *XMLNode) bool {
+	if p.Drop[node.Name] {
+		return true
+	}
+	if node.Parent == nil {
+		return false
+	}
+	// alternatives are chosen within their app or choice, even if one of them is grouped, e.g. in a rdgGrp
+	container := node.Parent
+	for alternativeGroups[container.Name] && container.Parent != nil {
+		container = container.Parent
+	}
+	for preferred, rejected := range p.Prefer {
+		if rejected == node.Name && hasAlternative(container, preferred) {
+			return true
+		}
+	}
+	return false
+}
+
+//alternativeGroups are the elements that group alternatives below an app or choice.
+var alternativeGroups = map[string]bool{"rdgGrp": true}
+
+//hasAlternative reports whether container has an alternative named name, directly or inside a group.
+func hasAlternative(container *XMLNode, name string) bool {
+	for _, child := range container.Children {
+		if child.Name == name || alternativeGroups[child.Name] && hasAlternative(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
+//applyElementPolicy removes the dropped elements from a passage and returns the remaining XML.
+func applyElementPolicy(fragment XMLFragment, policy ElementPolicy) string {
+	if fragment.Root == nil {
+		return fragment.Inner
+	}
+	return fragment.policyXML(fragment.Root, policy)
+}
+
+//policyXML returns the inner XML of node without the elements dropped by the policy.
+func (f XMLFragment) policyXML(node *XMLNode, policy ElementPolicy) string {
+	var result strings.Builder
+	last := node.InnerStart
+	var walk func(node *XMLNode)
+	walk = func(node *XMLNode) {
+		for _, child := range node.Children {
+			if policy.dropped(child) {
+				result.Write(f.Document[last:child.OuterStart])
+				last = child.OuterEnd
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(node)
+	result.Write(f.Document[last:node.InnerEnd])
+	return result.String()
+}
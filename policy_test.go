@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestApplyElementPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		inner  string
+		policy ElementPolicy
+		text   string
+	}{
+		{"lemma over reading", `<app><lem>good</lem><rdg>bad</rdg></app> text`, defaultElementPolicy(), "good text"},
+		{"lemma over grouped readings", `<app><lem>good</lem><rdgGrp><rdg>bad</rdg><rdg>worse</rdg></rdgGrp></app> text`, defaultElementPolicy(), "good text"},
+		{"reading without lemma", `<app><rdg>only</rdg></app>`, defaultElementPolicy(), "only"},
+		{"kept readings are not fused", `<app><lem>good</lem><rdgGrp><rdg>bad</rdg></rdgGrp></app>`, Options{Keep: []string{"rdg"}}.elementPolicy(), "good bad"},
+		{"correction over sic", `<choice><sic>teh</sic><corr>the</corr></choice> end`, defaultElementPolicy(), "the end"},
+		{"dropped note", `word<note>a note</note> next`, defaultElementPolicy(), "word next"},
+		{"nested app", `<app><lem>a <app><lem>b</lem><rdg>c</rdg></app></lem><rdg>d</rdg></app>`, defaultElementPolicy(), "a b"},
+	}
+	breaks := Options{}.breakElements()
+	for _, test := range tests {
+		if got := extractText(applyElementPolicy(parseFragment(test.inner), test.policy), breaks); got != test.text {
+			t.Errorf("%s: %q gives %q, want %q", test.name, test.inner, got, test.text)
+		}
+	}
+}
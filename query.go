@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+const queryUsage = "Usage: CTSExtract query [file.cex|SQLite database] [CTS URN, e.g. urn:cts:greekLit:tlg0012.tlg001.perseus-grc2:1.1-1.5]"
+
+//runQuery prints the passages of an extracted CEX file or SQLite database that match a CTS URN.
+func runQuery(args []string) error {
+	if len(args) != 2 {
+		return errors.New(queryUsage)
+	}
+	query, err := ctsurn.Parse(args[1])
+	if err != nil {
+		return err
+	}
+	var identifiers, texts []string
+	database, err := isSQLite(args[0])
+	if err != nil {
+		return err
+	}
+	if database {
+		identifiers, texts, err = readSQLPassages(args[0])
+	} else {
+		identifiers, texts, err = readCEXPassages(args[0])
+	}
+	if err != nil {
+		return err
+	}
+	identifiers, texts, err = queryPassages(identifiers, texts, query)
+	if err != nil {
+		return err
+	}
+	for i := range identifiers {
+		fmt.Println(identifiers[i] + "\t" + texts[i])
+	}
+	return nil
+}
+
+//sqliteHeader starts every SQLite database file.
+const sqliteHeader = "SQLite format 3\x00"
+
+//isSQLite reports whether file is a SQLite database, whatever its name.
+func isSQLite(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, header); err != nil {
+		// shorter than the header, so not a database
+		return false, nil
+	}
+	return string(header) == sqliteHeader, nil
+}
+
+//readCEXPassages reads the passages of a CEX file.
+func readCEXPassages(file string) (identifiers, texts []string, err error) {
+	library, err := readCEX(file)
+	return library.Identifiers, library.Texts, err
+}
+
+//readSQLPassages reads the passages table written by -SQL.
+func readSQLPassages(file string) (identifiers, texts []string, err error) {
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT urn, text FROM passages ORDER BY seq")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var identifier, text string
+		if err := rows.Scan(&identifier, &text); err != nil {
+			return nil, nil, err
+		}
+		identifiers = append(identifiers, identifier)
+		texts = append(texts, text)
+	}
+	return identifiers, texts, rows.Err()
+}
+
+//queryPassages selects the passages matching query in document order. A work or version URN selects all its passages, a passage selects it and the passages below it, a range everything from the first passage of its start to the last passage of its end. Subreferences cut the text of the first and last passage.
+func queryPassages(identifiers, texts []string, query ctsurn.URN) ([]string, []string, error) {
+	var resultIdentifiers, resultTexts []string
+	// a range is resolved separately in every version a work-level query matches
+	state := make(map[string]int)
+	const before, inside, after = 0, 1, 2
+	for i, identifier := range identifiers {
+		passage, err := ctsurn.Parse(identifier)
+		if err != nil || !sameText(passage, query) {
+			continue
+		}
+		text := texts[i]
+		reference := passage.Start.Reference
+		switch {
+		case query.Start.Reference == "":
+		case !query.IsRange():
+			if !withinReference(reference, query.Start.Reference) {
+				continue
+			}
+			if query.Start.Subreference != "" {
+				start, end, ok := findSubreference(text, query.Start)
+				if !ok {
+					continue
+				}
+				text = text[start:end]
+			}
+		default:
+			key := passage.Text().String()
+			if state[key] == before && withinReference(reference, query.Start.Reference) {
+				state[key] = inside
+				if query.Start.Subreference != "" {
+					if start, _, ok := findSubreference(text, query.Start); ok {
+						text = text[start:]
+					}
+				}
+			}
+			if state[key] != inside {
+				continue
+			}
+			if withinReference(reference, query.End.Reference) && !nextWithin(identifiers, i, query.End.Reference, key) {
+				state[key] = after
+				if query.End.Subreference != "" {
+					cut := len(texts[i]) - len(text)
+					if _, end, ok := findSubreference(texts[i], query.End); ok && end > cut {
+						text = text[:end-cut]
+					}
+				}
+			}
+		}
+		resultIdentifiers = append(resultIdentifiers, identifier)
+		resultTexts = append(resultTexts, text)
+	}
+	if len(resultIdentifiers) == 0 {
+		return nil, nil, fmt.Errorf("no passage matches %s", query)
+	}
+	return resultIdentifiers, resultTexts, nil
+}
+
+//sameText reports whether passage belongs to the work, version or exemplar of query. Exemplars only match if they are asked for.
+func sameText(passage, query ctsurn.URN) bool {
+	if passage.Namespace != query.Namespace || passage.Textgroup != query.Textgroup {
+		return false
+	}
+	if query.Work != "" && passage.Work != query.Work {
+		return false
+	}
+	if query.Version != "" && passage.Version != query.Version {
+		return false
+	}
+	return passage.Exemplar == query.Exemplar
+}
+
+//withinReference reports whether reference is the reference of the query or lies below it, e.g. 1.2 within 1.
+func withinReference(reference, query string) bool {
+	return reference == query || strings.HasPrefix(reference, query+".")
+}
+
+//nextWithin reports whether the next passage of the same text also lies within reference, so that a range ending at a book includes all of its lines.
+func nextWithin(identifiers []string, i int, reference, text string) bool {
+	for _, identifier := range identifiers[i+1:] {
+		passage, err := ctsurn.Parse(identifier)
+		if err != nil || passage.Text().String() != text {
+			continue
+		}
+		return withinReference(passage.Start.Reference, reference)
+	}
+	return false
+}
+
+//findSubreference returns the byte offsets of the subreference of position in text, counting occurrences from 1.
+func findSubreference(text string, position ctsurn.Position) (int, int, bool) {
+	index := position.Index
+	if index == 0 {
+		index = 1
+	}
+	offset := 0
+	for n := 1; ; n++ {
+		i := strings.Index(text[offset:], position.Subreference)
+		if i == -1 {
+			return 0, 0, false
+		}
+		if n == index {
+			return offset + i, offset + i + len(position.Subreference), true
+		}
+		offset = offset + i + len(position.Subreference)
+	}
+}
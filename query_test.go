@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+func TestQueryPassages(t *testing.T) {
+	const grc = "urn:cts:greekLit:tlg0012.tlg001.perseus-grc2:"
+	const eng = "urn:cts:greekLit:tlg0012.tlg001.perseus-eng3:"
+	identifiers := []string{
+		grc + "1.1", grc + "1.2", grc + "2.1", grc + "2.2", grc + "3.1",
+		eng + "1.1", eng + "1.2", eng + "2.1",
+		"urn:cts:greekLit:tlg0012.tlg001.perseus-grc2.tokens:1.1.1",
+		"urn:cts:greekLit:tlg0012.tlg002.perseus-grc2:1.1",
+	}
+	texts := []string{
+		"μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος", "οὐλομένην, ἣ μυρί᾽ Ἀχαιοῖς ἄλγε᾽ ἔθηκε", "ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες", "εὗδον παννύχιοι, Δία δ᾽ οὐκ ἔχε", "θεὰ θεὰ θεὰ",
+		"Sing, goddess, the anger", "the accursed anger", "Now the other gods",
+		"μῆνιν",
+		"ἄνδρα μοι ἔννεπε",
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+		texts []string
+	}{
+		{"work", "urn:cts:greekLit:tlg0012.tlg001:", []string{grc + "1.1", grc + "1.2", grc + "2.1", grc + "2.2", grc + "3.1", eng + "1.1", eng + "1.2", eng + "2.1"}, nil},
+		{"version", "urn:cts:greekLit:tlg0012.tlg001.perseus-eng3", []string{eng + "1.1", eng + "1.2", eng + "2.1"}, nil},
+		{"exemplar", "urn:cts:greekLit:tlg0012.tlg001.perseus-grc2.tokens:", []string{"urn:cts:greekLit:tlg0012.tlg001.perseus-grc2.tokens:1.1.1"}, nil},
+		{"passage", grc + "1.2", []string{grc + "1.2"}, nil},
+		{"container", grc + "2", []string{grc + "2.1", grc + "2.2"}, nil},
+		{"range", grc + "1.2-2.1", []string{grc + "1.2", grc + "2.1"}, nil},
+		{"range to container", grc + "1.2-2", []string{grc + "1.2", grc + "2.1", grc + "2.2"}, nil},
+		{"range from container", grc + "2-3.1", []string{grc + "2.1", grc + "2.2", grc + "3.1"}, nil},
+		{"range in every version of a work", "urn:cts:greekLit:tlg0012.tlg001:1.2-2.1", []string{grc + "1.2", grc + "2.1", eng + "1.2", eng + "2.1"}, nil},
+		{"subreference", grc + "1.1@θεὰ", []string{grc + "1.1"}, []string{"θεὰ"}},
+		{"indexed subreference", grc + "3.1@θεὰ[2]", []string{grc + "3.1"}, []string{"θεὰ"}},
+		{"indexed subreferences within one passage", grc + "3.1@θεὰ[2]-3.1@θεὰ[3]", []string{grc + "3.1"}, []string{"θεὰ θεὰ"}},
+		{"missing subreference", grc + "1.1@Ἀχαιοῖς", nil, nil},
+		{"range between subreferences", grc + "1.1@θεὰ-1.2@μυρί᾽", []string{grc + "1.1", grc + "1.2"}, []string{"θεὰ Πηληϊάδεω Ἀχιλῆος", "οὐλομένην, ἣ μυρί᾽"}},
+		{"subreferences within one passage", grc + "1.1@ἄειδε-1.1@Πηληϊάδεω", []string{grc + "1.1"}, []string{"ἄειδε θεὰ Πηληϊάδεω"}},
+		{"no match", grc + "9", nil, nil},
+	}
+	for _, test := range tests {
+		query, err := ctsurn.Parse(test.query)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		got, gotTexts, err := queryPassages(identifiers, texts, query)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: %s matches %q, want no passage", test.name, test.query, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s: %v", test.name, test.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: %s matches %q, want %q", test.name, test.query, got, test.want)
+		}
+		if test.texts != nil && !reflect.DeepEqual(gotTexts, test.texts) {
+			t.Errorf("%s: %s gives %q, want %q", test.name, test.query, gotTexts, test.texts)
+		}
+	}
+}
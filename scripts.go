@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ThomasK81/TEItoCEX/tokenizer"
+)
+
+//defaultScripts are counted when no -Scripts are given.
+var defaultScripts = []string{"Greek", "Latin", "Arabic"}
+
+//WordCounts container for the number of words per Unicode script of every passage. Passage[i][j] is the count of Scripts[j] in the i-th passage. A word counts for the script of its first letter.
+type WordCounts struct {
+	Scripts []string
+	Passage [][]int
+	Total   []int
+	tables  []*unicode.RangeTable
+}
+
+//scripts returns the Unicode scripts set with -Scripts, or the default ones.
+func (o Options) scripts() []string {
+	if len(o.Scripts) == 0 {
+		return defaultScripts
+	}
+	return o.Scripts
+}
+
+func newWordCounts(scripts []string) WordCounts {
+	counts := WordCounts{Scripts: scripts, Total: make([]int, len(scripts))}
+	for _, v := range scripts {
+		counts.tables = append(counts.tables, unicode.Scripts[v])
+	}
+	return counts
+}
+
+//add counts the words of every script in the text of the next passage.
+func (w *WordCounts) add(text string) {
+	counts := make([]int, len(w.Scripts))
+	for _, word := range tokenizer.Words(tokenizer.Tokenize(text, tokenizer.Options{})) {
+		for i, v := range w.tables {
+			if unicode.Is(v, firstLetter(word)) {
+				counts[i]++
+				w.Total[i]++
+			}
+		}
+	}
+	w.Passage = append(w.Passage, counts)
+}
+
+//skip adds a passage whose words are not counted, e.g. a passage of an exemplar.
+func (w *WordCounts) skip() {
+	w.Passage = append(w.Passage, make([]int, len(w.Scripts)))
+}
+
+//work sums the counts of all passages of the version urn.
+func (w WordCounts) work(identifiers []string, urn string) []int {
+	counts := make([]int, len(w.Scripts))
+	for i, v := range identifiers {
+		if !strings.HasPrefix(v, urn+":") {
+			continue
+		}
+		for j := range w.Scripts {
+			counts[j] = counts[j] + w.Passage[i][j]
+		}
+	}
+	return counts
+}
+
+//byScript returns counts keyed by script name.
+func (w WordCounts) byScript(counts []int) map[string]int {
+	result := make(map[string]int)
+	for i, v := range w.Scripts {
+		result[v] = counts[i]
+	}
+	return result
+}
+
+func firstLetter(word string) rune {
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return r
+		}
+	}
+	return 0
+}
+
+func sum(counts []int) int {
+	total := 0
+	for _, v := range counts {
+		total = total + v
+	}
+	return total
+}
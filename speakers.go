@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strconv"
+)
+
+const speakersCollectionURN = citeNamespace + "speakers.v1:"
+const spokenByVerb = citeNamespace + "verbs.v1:spokenBy"
+
+//SpeakerCollection container for the speakers of the dramatic texts, one object per speaker and version
+type SpeakerCollection struct {
+	URN      []string `json:"urn"`
+	Name     []string `json:"name"`
+	Text     []string `json:"text"`
+	Lines    []int    `json:"lines"`
+	Passages []string `json:"passages"`
+	Speakers []string `json:"speakers"`
+	index    map[string]int
+}
+
+//speakerOf returns the speaker of the nearest tei:sp at or above node. The text of its tei:speaker is used, or its who attribute if it has none.
+func speakerOf(node *XMLNode, document []byte, breaks map[string]bool) string {
+	for sp := node; sp != nil; sp = sp.Parent {
+		if sp.Name != "sp" {
+			continue
+		}
+		for _, speaker := range childrenNamed(sp, "speaker") {
+			if name := extractText(speaker.InnerXML(document), breaks); name != "" {
+				return name
+			}
+		}
+		return pointerList(sp.Attrs["who"])
+	}
+	return ""
+}
+
+//add counts passage as a line of speaker in the version text.
+func (c *SpeakerCollection) add(text, passage, speaker string) {
+	if c.index == nil {
+		c.index = make(map[string]int)
+	}
+	key := text + "#" + speaker
+	i, ok := c.index[key]
+	if !ok {
+		i = len(c.URN)
+		c.index[key] = i
+		c.URN = append(c.URN, speakersCollectionURN+strconv.Itoa(i+1))
+		c.Name = append(c.Name, speaker)
+		c.Text = append(c.Text, text)
+		c.Lines = append(c.Lines, 0)
+	}
+	c.Lines[i]++
+	c.Passages = append(c.Passages, passage)
+	c.Speakers = append(c.Speakers, c.URN[i])
+}
+
+func (c SpeakerCollection) citeCollection() CiteCollection {
+	collection := CiteCollection{
+		URN:         speakersCollectionURN,
+		Description: "Speakers of the dramatic texts with their number of lines",
+		Labelling:   "name",
+		License:     defaultLicense,
+		Model:       CiteDataModel{Model: citeNamespace + "datamodels.v1:speakers", Label: "Speakers", Description: "Speakers linked to the passages they speak by " + spokenByVerb},
+		Properties: []CiteProperty{
+			{Name: "urn", Label: "Speaker", Type: "Cite2Urn"},
+			{Name: "name", Label: "Name", Type: "String"},
+			{Name: "text", Label: "Text", Type: "CtsUrn"},
+			{Name: "lines", Label: "Lines", Type: "Number"},
+		},
+	}
+	for i := range c.URN {
+		collection.Rows = append(collection.Rows, []string{c.URN[i], c.Name[i], c.Text[i], strconv.Itoa(c.Lines[i])})
+	}
+	return collection
+}
+
+//relations links every passage to its speaker.
+func (c SpeakerCollection) relations(relations *CiteRelations) {
+	for i := range c.Passages {
+		relations.add(c.Passages[i], spokenByVerb, c.Speakers[i])
+	}
+}
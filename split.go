@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+const partsCollectionURN = citeNamespace + "parts.v1:"
+const containedInVerb = citeNamespace + "verbs.v1:containedIn"
+
+//splitUnits are the values of -Split.
+var splitUnits = map[string]bool{"textgroup": true, "work": true}
+
+//splitKey returns the textgroup or work of a CTS URN, e.g. tlg0012 or tlg0012.tlg001. Values that are not CTS URNs have none.
+func splitKey(value, unit string) (string, bool) {
+	if !strings.HasPrefix(value, "urn:cts:") {
+		return "", false
+	}
+	parsed, err := ctsurn.Parse(value)
+	if err != nil {
+		return "", false
+	}
+	if unit == "textgroup" {
+		return parsed.Textgroup, true
+	}
+	return parsed.Textgroup + "." + parsed.Work, true
+}
+
+//rowKey returns the textgroup or work of the first CTS URN among values.
+func rowKey(values []string, unit string) (string, bool) {
+	for _, v := range values {
+		if key, ok := splitKey(v, unit); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+//appendRow adds row i of from to the catalog.
+func (c *CTSCatalog) appendRow(from CTSCatalog, i int) {
+	c.URN = append(c.URN, from.URN[i])
+	c.CitationScheme = append(c.CitationScheme, from.CitationScheme[i])
+	c.GroupName = append(c.GroupName, from.GroupName[i])
+	c.WorkTitle = append(c.WorkTitle, from.WorkTitle[i])
+	c.VersionLabel = append(c.VersionLabel, from.VersionLabel[i])
+	c.ExemplarLabel = append(c.ExemplarLabel, from.ExemplarLabel[i])
+	c.Online = append(c.Online, from.Online[i])
+	c.Language = append(c.Language, from.Language[i])
+	c.Description = append(c.Description, from.Description[i])
+	c.VersionType = append(c.VersionType, from.VersionType[i])
+	c.Contributors = append(c.Contributors, from.Contributors[i])
+}
+
+//addRow adds a row of collection c, creating the collection in the library with its first row.
+func (l *CEXLibrary) addRow(c CiteCollection, row []string) {
+	for i := range l.Collections {
+		if l.Collections[i].URN == c.URN {
+			l.Collections[i].Rows = append(l.Collections[i].Rows, row)
+			return
+		}
+	}
+	c.Rows = [][]string{row}
+	l.Collections = append(l.Collections, c)
+}
+
+//splitLibrary distributes the catalog, the passages, the collection rows and the relations over the textgroups or works they cite. The keys are returned in catalog order. Collection rows and relations that cite no text are returned separately, so that they can be written to the index.
+func splitLibrary(unit string, ctscatalog CTSCatalog, identifiers, texts []string, collections []CiteCollection, relations CiteRelations, datamodels []CiteDataModel) (keys []string, parts map[string]*CEXLibrary, rest CEXLibrary) {
+	parts = make(map[string]*CEXLibrary)
+	part := func(key string) *CEXLibrary {
+		if parts[key] == nil {
+			parts[key] = &CEXLibrary{}
+			keys = append(keys, key)
+		}
+		return parts[key]
+	}
+	for i, v := range ctscatalog.URN {
+		if key, ok := splitKey(v, unit); ok {
+			part(key).Catalog.appendRow(ctscatalog, i)
+		}
+	}
+	for i, v := range identifiers {
+		if key, ok := splitKey(v, unit); ok && parts[key] != nil {
+			parts[key].Identifiers = append(parts[key].Identifiers, v)
+			parts[key].Texts = append(parts[key].Texts, texts[i])
+		}
+	}
+	for _, c := range collections {
+		if len(c.Rows) == 0 {
+			rest.Collections = append(rest.Collections, c)
+			continue
+		}
+		for _, row := range c.Rows {
+			if key, ok := rowKey(row, unit); ok && parts[key] != nil {
+				parts[key].addRow(c, row)
+				continue
+			}
+			rest.addRow(c, row)
+		}
+	}
+	for i := range relations.Subject {
+		target := &rest
+		if key, ok := rowKey([]string{relations.Subject[i], relations.Object[i]}, unit); ok && parts[key] != nil {
+			target = parts[key]
+		}
+		target.Relations.add(relations.Subject[i], relations.Verb[i], relations.Object[i])
+	}
+	libraries := []*CEXLibrary{&rest}
+	for _, key := range keys {
+		libraries = append(libraries, parts[key])
+	}
+	for _, l := range libraries {
+		for _, v := range datamodels {
+			if l.collection(v.Collection) != nil {
+				l.DataModels = append(l.DataModels, v)
+			}
+		}
+	}
+	return keys, parts, rest
+}
+
+//partLibraryURN returns the URN of the library of a part, e.g. urn:cite2:teitocex:library.v1:corpus.tlg0012 for the library urn:cite2:teitocex:library.v1:corpus
+func partLibraryURN(urn, key string) string {
+	if strings.HasSuffix(urn, ":") {
+		return urn + key
+	}
+	return urn + "." + key
+}
+
+//writeSplitCEX writes one CEX file per textgroup or work next to outputFile, e.g. corpus_tlg0012.cex, each with the catalog rows, passages, collection rows and relations of its texts. outputFile becomes an index CEX: the collection urn:cite2:teitocex:parts.v1: lists the files, and its relations tell which file holds a text.
+func writeSplitCEX(outputFile string, options Options, citelibrary CiteLibrary, ctscatalog CTSCatalog, identifiers, texts []string, collections []CiteCollection, relations CiteRelations, datamodels []CiteDataModel) {
+	keys, parts, index := splitLibrary(options.Split, ctscatalog, identifiers, texts, collections, relations, datamodels)
+	files := CiteCollection{
+		URN:         partsCollectionURN,
+		Description: "CEX files of the library, one per " + options.Split,
+		Labelling:   "label",
+		License:     citelibrary.License,
+		Properties: []CiteProperty{
+			{Name: "urn", Label: "File", Type: "Cite2Urn"},
+			{Name: "file", Label: "File name", Type: "String"},
+			{Name: options.Split, Label: strings.Title(options.Split), Type: "String"},
+			{Name: "label", Label: "Label", Type: "String"},
+			{Name: "passages", Label: "Passages", Type: "Number"},
+		},
+	}
+	for _, key := range keys {
+		part := parts[key]
+		partFile := sideFile(outputFile, key, ".cex")
+		label := part.Catalog.GroupName[0]
+		if options.Split == "work" {
+			label = part.Catalog.WorkTitle[0]
+		}
+		fmt.Println("Writing CEX-File", partFile)
+		partlibrary := CiteLibrary{Name: citelibrary.Name + ": " + label, URN: partLibraryURN(citelibrary.URN, key), License: citelibrary.License}
+		delimiter := options.delimiter(cexValues(partlibrary, part.Catalog, part.Identifiers, part.Texts, part.Collections, part.Relations, part.DataModels)...)
+		writeCEX(partFile, delimiter, partlibrary, part.Catalog, part.Identifiers, part.Texts, part.Collections, part.Relations, part.DataModels)
+		files.Rows = append(files.Rows, []string{partsCollectionURN + key, filepath.Base(partFile), key, label, strconv.Itoa(len(part.Identifiers))})
+		for _, v := range part.Catalog.URN {
+			index.Relations.add(v, containedInVerb, partsCollectionURN+key)
+		}
+	}
+	index.Collections = append([]CiteCollection{files}, index.Collections...)
+	fmt.Println("Writing index CEX-File", outputFile)
+	delimiter := options.delimiter(cexValues(citelibrary, CTSCatalog{}, nil, nil, index.Collections, index.Relations, index.DataModels)...)
+	writeIndexCEX(outputFile, delimiter, citelibrary, index.Collections, index.Relations, index.DataModels)
+}
+
+//writeIndexCEX writes a CEX file without texts.
+func writeIndexCEX(outputFile string, delimiter string, citelibrary CiteLibrary, collections []CiteCollection, relations CiteRelations, datamodels []CiteDataModel) {
+	f, err := os.Create(outputFile)
+	check(err)
+	fconnection := fileConnection{f}
+	defer f.Close()
+
+	writeCEXVersion(fconnection)
+	writeCiteLibrary(fconnection, delimiter, citelibrary)
+	writeCiteCollections(fconnection, delimiter, collections)
+	writeRelations(fconnection, delimiter, relations)
+	writeDataModels(fconnection, delimiter, datamodels)
+}
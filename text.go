@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var tagsRegExp = regexp.MustCompile(`<[/]*[^>]*>`)
+var insideWhitespaceRegExp = regexp.MustCompile(`[\s\p{Zs}]{2,}`)
+
+//defaultBreakElements are the block and line-break elements at which a space is inserted.
+var defaultBreakElements = []string{"ab", "br", "cb", "div", "head", "item", "l", "lb", "lem", "lg", "milestone", "p", "pb", "rdg", "sp", "speaker"}
+
+//breakElements returns the elements set with -Breaks, or the default ones.
+func (o Options) breakElements() map[string]bool {
+	elements := defaultBreakElements
+	if len(o.Breaks) > 0 {
+		elements = o.Breaks
+	}
+	breaks := make(map[string]bool)
+	for _, v := range elements {
+		breaks[v] = true
+	}
+	return breaks
+}
+
+//extractText returns the text of a passage. Entity references are decoded, comments, processing instructions and directives are skipped, and a space is inserted at the start and end of every break element so that words separated only by tags (e.g. <lb/>) are not fused.
+func extractText(inner string, breaks map[string]bool) string {
+	var result strings.Builder
+	decoder := newLenientDecoder([]byte(inner))
+	for {
+		t, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return collapseWhitespace(tagsRegExp.ReplaceAllString(inner, ""))
+		}
+		switch se := t.(type) {
+		case xml.CharData:
+			result.Write(se)
+		case xml.StartElement:
+			if breaks[se.Name.Local] {
+				result.WriteString(" ")
+			}
+		case xml.EndElement:
+			if breaks[se.Name.Local] {
+				result.WriteString(" ")
+			}
+		}
+	}
+	return collapseWhitespace(result.String())
+}
+
+//stringcleaning returns the text of a passage using the default break elements.
+func stringcleaning(text string) string {
+	return extractText(text, Options{}.breakElements())
+}
+
+//collapseWhitespace turns newlines into spaces and reduces runs of whitespace to a single space.
+func collapseWhitespace(text string) string {
+	result := strings.Replace(text, "\n", " ", -1)
+	result = strings.TrimSpace(result)
+	result = insideWhitespaceRegExp.ReplaceAllString(result, " ")
+	return result
+}
@@ -0,0 +1,186 @@
+//Package tokenizer splits the reading text of Greek and Latin passages into words, numerals and punctuation.
+//
+//Combining diacritics stay with their letter, so decomposed (NFD) text gives the same words as composed text. An apostrophe or koronis after a letter belongs to the word, so elided forms like δ᾽ or ἀλλ᾽ and crasis like κ᾽ἀγώ are single words; one before a letter marks aphaeresis and belongs to the following word. The Latin enclitic -que can optionally be split off, and on request also -ne and -ve.
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+)
+
+//Kind is the kind of a token.
+type Kind int
+
+const (
+	//Word is a run of letters, combining marks and inner apostrophes.
+	Word Kind = iota
+	//Enclitic is a Latin enclitic split off the preceding word.
+	Enclitic
+	//Numeral is a run of digits, or a Greek alphabetic numeral marked with a keraia.
+	Numeral
+	//Punctuation is a single punctuation mark or symbol.
+	Punctuation
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Word:
+		return "word"
+	case Enclitic:
+		return "enclitic"
+	case Numeral:
+		return "numeral"
+	case Punctuation:
+		return "punctuation"
+	}
+	return "unknown"
+}
+
+//Token is one token of a text.
+type Token struct {
+	Text string
+	Kind Kind
+}
+
+//Options modify the tokenization.
+type Options struct {
+	//LatinEnclitics splits -que off Latin words, e.g. arma|que.
+	LatinEnclitics bool
+	//NeVe also splits -ne and -ve off Latin words, e.g. vides|ne. As many words end in them without an enclitic (carmine, ratione, grave), only stems ending in a consonant are split.
+	NeVe bool
+}
+
+//keraias are the Greek numeral sign and the modifier letter prime it normalizes to.
+const keraias = "ʹʹ"
+
+//apostrophes are the characters used for elision, crasis and aphaeresis: the apostrophe, right single quotation mark, Greek koronis, psili and modifier letter apostrophe.
+const apostrophes = "'’᾽᾿ʼ"
+
+func isApostrophe(r rune) bool {
+	return strings.ContainsRune(apostrophes, r)
+}
+
+func isKeraia(r rune) bool {
+	return strings.ContainsRune(keraias, r)
+}
+
+func isLetter(r rune) bool {
+	return !isKeraia(r) && (unicode.IsLetter(r) || unicode.Is(unicode.M, r))
+}
+
+//Tokenize splits text into tokens. White space separates tokens and is dropped.
+func Tokenize(text string, options Options) []Token {
+	var tokens []Token
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case isLetter(r) || (isApostrophe(r) && i+1 < len(runes) && unicode.IsLetter(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (isLetter(runes[i]) || isApostrophe(runes[i])) {
+				i++
+			}
+			if i < len(runes) && isKeraia(runes[i]) {
+				i++
+				tokens = append(tokens, Token{Text: string(runes[start:i]), Kind: Numeral})
+				continue
+			}
+			word := string(runes[start:i])
+			if options.LatinEnclitics || options.NeVe {
+				if stem, enclitic, ok := splitEnclitic(word, options.NeVe); ok {
+					tokens = append(tokens, Token{Text: stem, Kind: Word}, Token{Text: enclitic, Kind: Enclitic})
+					continue
+				}
+			}
+			tokens = append(tokens, Token{Text: word, Kind: Word})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.Is(unicode.M, runes[i])) {
+				i++
+			}
+			tokens = append(tokens, Token{Text: string(runes[start:i]), Kind: Numeral})
+		default:
+			i++
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				tokens = append(tokens, Token{Text: string(r), Kind: Punctuation})
+			}
+		}
+	}
+	return tokens
+}
+
+//Words returns the text of the word tokens only.
+func Words(tokens []Token) []string {
+	var words []string
+	for _, v := range tokens {
+		if v.Kind == Word {
+			words = append(words, v.Text)
+		}
+	}
+	return words
+}
+
+//encliticExceptions are Latin words that end in an enclitic without having one.
+var encliticExceptions = map[string]bool{
+	"atque": true, "quoque": true, "neque": true, "itaque": true, "usque": true, "absque": true, "namque": true,
+	"denique": true, "undique": true, "ubique": true, "utique": true, "plerumque": true, "utrimque": true, "quisque": true,
+	"quaeque": true, "quodque": true, "quidque": true, "quicque": true, "cuique": true, "quemque": true, "quamque": true,
+	"uterque": true, "utraque": true, "utrumque": true, "quique": true, "cumque": true, "susque": true,
+	"bene": true, "sine": true, "paene": true, "pone": true, "mane": true, "tune": true, "superne": true, "inferne": true,
+	"siue": true, "sive": true, "neue": true, "neve": true, "ne": true, "que": true, "ve": true, "ue": true,
+	// words ending in -ne or -ve after a consonant
+	"nonne": true, "omne": true, "sollemne": true, "perenne": true, "penne": true, "pinne": true,
+	"magne": true, "digne": true, "indigne": true, "benigne": true, "maligne": true, "insigne": true, "signe": true,
+	"aeterne": true, "alterne": true, "interne": true, "externe": true, "hodierne": true, "paterne": true, "materne": true,
+	"fraterne": true, "diurne": true, "nocturne": true, "eburne": true, "taciturne": true, "sempiterne": true, "verne": true,
+	"salve": true, "solve": true, "absolve": true, "resolve": true, "volve": true, "revolve": true, "evolve": true,
+	"serve": true, "conserve": true, "observe": true, "ferve": true, "parve": true, "curve": true, "torve": true,
+	"proterve": true, "calve": true, "fulve": true, "helve": true,
+}
+
+//splitEnclitic splits a Latin enclitic off word. Words in other scripts, exceptions and words that would leave a stem shorter than two letters are not split. -ne and -ve are only split if neve is set and the stem ends in a consonant.
+func splitEnclitic(word string, neve bool) (string, string, bool) {
+	lower := strings.ToLower(word)
+	if encliticExceptions[lower] || !isLatin(word) {
+		return word, "", false
+	}
+	enclitics := []string{"que"}
+	if neve {
+		enclitics = append(enclitics, "ne", "ve")
+	}
+	for _, v := range enclitics {
+		if !strings.HasSuffix(lower, v) || len(lower)-len(v) < 2 {
+			continue
+		}
+		stem := word[:len(word)-len(v)]
+		if v != "que" && endsInVowel(stem) {
+			continue
+		}
+		return stem, word[len(word)-len(v):], true
+	}
+	return word, "", false
+}
+
+//endsInVowel reports whether the last letter of word, ignoring diacritics, is a vowel.
+func endsInVowel(word string) bool {
+	runes := []rune(word)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.Is(unicode.M, runes[i]) {
+			continue
+		}
+		return strings.ContainsRune("aeiouyāēīōūȳăĕĭŏŭæœ", unicode.ToLower(runes[i]))
+	}
+	return false
+}
+
+func isLatin(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		tokens []Token
+	}{
+		{"words and punctuation", "μῆνιν ἄειδε, θεά·", []Token{{"μῆνιν", Word}, {"ἄειδε", Word}, {",", Punctuation}, {"θεά", Word}, {"·", Punctuation}}},
+		{"elision", "ἀλλ᾽ ἐπὶ δ’ ἄλγε᾽ ἔθηκε", []Token{{"ἀλλ᾽", Word}, {"ἐπὶ", Word}, {"δ’", Word}, {"ἄλγε᾽", Word}, {"ἔθηκε", Word}}},
+		{"crasis", "κ᾽ἀγώ τοὔνομα", []Token{{"κ᾽ἀγώ", Word}, {"τοὔνομα", Word}}},
+		{"aphaeresis", "ὦ ’γαθέ", []Token{{"ὦ", Word}, {"’γαθέ", Word}}},
+		{"keraia numeral", "ἔτει ιβ\u0374", []Token{{"ἔτει", Word}, {"ιβ\u0374", Numeral}}},
+		{"modifier letter prime as keraia", "ἔτει ιβ\u02b9", []Token{{"ἔτει", Word}, {"ιβ\u02b9", Numeral}}},
+		{"digits", "anno 1453.", []Token{{"anno", Word}, {"1453", Numeral}, {".", Punctuation}}},
+		{"enclitics are kept by default", "arma virumque", []Token{{"arma", Word}, {"virumque", Word}}},
+	}
+	for _, test := range tests {
+		if got := Tokenize(test.text, Options{}); !reflect.DeepEqual(got, test.tokens) {
+			t.Errorf("%s: Tokenize(%q) = %v, want %v", test.name, test.text, got, test.tokens)
+		}
+	}
+}
+
+func TestTokenizeDecomposed(t *testing.T) {
+	text := "μῆνιν ἄειδε θεὰ Πηληϊάδεω"
+	composed := Words(Tokenize(norm.NFC.String(text), Options{}))
+	decomposed := Words(Tokenize(norm.NFD.String(text), Options{}))
+	if len(composed) != 4 || len(decomposed) != len(composed) {
+		t.Errorf("NFC gives %q, NFD gives %q", composed, decomposed)
+	}
+}
+
+func TestEnclitics(t *testing.T) {
+	tests := []struct {
+		word    string
+		options Options
+		split   []Token
+	}{
+		{"virumque", Options{LatinEnclitics: true}, []Token{{"virum", Word}, {"que", Enclitic}}},
+		{"Arma", Options{LatinEnclitics: true}, []Token{{"Arma", Word}}},
+		{"atque", Options{LatinEnclitics: true}, []Token{{"atque", Word}}},
+		{"quisque", Options{LatinEnclitics: true}, []Token{{"quisque", Word}}},
+		{"videsne", Options{LatinEnclitics: true}, []Token{{"videsne", Word}}},
+		{"τέ", Options{LatinEnclitics: true, NeVe: true}, []Token{{"τέ", Word}}},
+		{"videsne", Options{LatinEnclitics: true, NeVe: true}, []Token{{"vides", Word}, {"ne", Enclitic}}},
+		{"pluresve", Options{LatinEnclitics: true, NeVe: true}, []Token{{"plures", Word}, {"ve", Enclitic}}},
+		{"quisve", Options{LatinEnclitics: true, NeVe: true}, []Token{{"quis", Word}, {"ve", Enclitic}}},
+		{"carmine", Options{LatinEnclitics: true, NeVe: true}, []Token{{"carmine", Word}}},
+		{"homine", Options{LatinEnclitics: true, NeVe: true}, []Token{{"homine", Word}}},
+		{"ratione", Options{LatinEnclitics: true, NeVe: true}, []Token{{"ratione", Word}}},
+		{"grave", Options{LatinEnclitics: true, NeVe: true}, []Token{{"grave", Word}}},
+		{"omne", Options{LatinEnclitics: true, NeVe: true}, []Token{{"omne", Word}}},
+		{"salve", Options{LatinEnclitics: true, NeVe: true}, []Token{{"salve", Word}}},
+		{"nonne", Options{LatinEnclitics: true, NeVe: true}, []Token{{"nonne", Word}}},
+		{"sine", Options{LatinEnclitics: true, NeVe: true}, []Token{{"sine", Word}}},
+	}
+	for _, test := range tests {
+		if got := Tokenize(test.word, test.options); !reflect.DeepEqual(got, test.split) {
+			t.Errorf("Tokenize(%q, %+v) = %v, want %v", test.word, test.options, got, test.split)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tokens := Tokenize("arma virumque cano, 12", Options{LatinEnclitics: true})
+	want := []string{"arma", "virum", "cano"}
+	if got := Words(tokens); !reflect.DeepEqual(got, want) {
+		t.Errorf("Words = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+	"github.com/ThomasK81/TEItoCEX/tokenizer"
+)
+
+const tokensExemplar = "tokens"
+
+//addTokenExemplars adds a tokenized exemplar for every version of the catalog. The exemplar cites one level below the version, every token of a passage is cited by its index, e.g. urn:cts:greekLit:tlg0012.tlg001.perseus-grc2.tokens:1.1.3. Versions that are already exemplars, and versions whose tokenized exemplar is in the catalog already (e.g. read with -Input), are skipped.
+func addTokenExemplars(ctscatalog CTSCatalog, identifiers, texts []string, options tokenizer.Options) (CTSCatalog, []string, []string) {
+	tokenized := make(map[string]bool)
+	catalogued := make(map[string]bool)
+	for _, urn := range ctscatalog.URN {
+		catalogued[urn] = true
+	}
+	for i, urn := range append([]string{}, ctscatalog.URN...) {
+		versionurn, err := ctsurn.Parse(urn)
+		if err != nil || versionurn.Exemplar != "" || versionurn.Version == "" {
+			if err != nil || versionurn.Exemplar != tokensExemplar {
+				fmt.Println(urn, "is not a version, no tokenized exemplar is written.")
+			}
+			continue
+		}
+		if catalogued[versionurn.WithExemplar(tokensExemplar).String()] {
+			continue
+		}
+		tokenized[urn] = true
+		ctscatalog.URN = append(ctscatalog.URN, versionurn.WithExemplar(tokensExemplar).String())
+		ctscatalog.CitationScheme = append(ctscatalog.CitationScheme, ctscatalog.CitationScheme[i]+",token")
+		ctscatalog.GroupName = append(ctscatalog.GroupName, ctscatalog.GroupName[i])
+		ctscatalog.WorkTitle = append(ctscatalog.WorkTitle, ctscatalog.WorkTitle[i])
+		ctscatalog.VersionLabel = append(ctscatalog.VersionLabel, ctscatalog.VersionLabel[i])
+		ctscatalog.ExemplarLabel = append(ctscatalog.ExemplarLabel, "Tokenized exemplar")
+		ctscatalog.Description = append(ctscatalog.Description, ctscatalog.Description[i])
+		ctscatalog.VersionType = append(ctscatalog.VersionType, ctscatalog.VersionType[i])
+		ctscatalog.Online = append(ctscatalog.Online, ctscatalog.Online[i])
+		ctscatalog.Language = append(ctscatalog.Language, ctscatalog.Language[i])
+		ctscatalog.Contributors = append(ctscatalog.Contributors, ctscatalog.Contributors[i])
+	}
+	for i, identifier := range append([]string{}, identifiers...) {
+		passageurn, err := ctsurn.Parse(identifier)
+		if err != nil || !tokenized[passageurn.Text().String()] {
+			continue
+		}
+		exemplar := passageurn.WithExemplar(tokensExemplar)
+		for j, token := range tokenizer.Tokenize(texts[i], options) {
+			identifiers = append(identifiers, exemplar.WithPassage(passageurn.Start.Reference, strconv.Itoa(j+1)).String())
+			texts = append(texts, token.Text)
+		}
+	}
+	return ctscatalog, identifiers, texts
+}
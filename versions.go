@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ThomasK81/TEItoCEX/ctsurn"
+)
+
+const defaultNamespace = "urn:cts:greekLit:"
+const translatesVerb = citeNamespace + "verbs.v1:translates"
+
+//versionTypes are the values of div/@type that mark a CTS version inside the body.
+var versionTypes = map[string]bool{"edition": true, "translation": true, "commentary": true}
+
+//versionDivs returns the divs of the body that hold a version, e.g. div[@type='edition'] and div[@type='translation'].
+func versionDivs(root *XMLNode) []*XMLNode {
+	var divs []*XMLNode
+	for _, text := range childrenNamed(root, "text") {
+		for _, body := range childrenNamed(text, "body") {
+			for _, div := range childrenNamed(body, "div") {
+				if versionTypes[div.Attrs["type"]] {
+					divs = append(divs, div)
+				}
+			}
+		}
+	}
+	return divs
+}
+
+//separateVersions returns the version divs of a file that carry their own CTS URN in @n. Only if there are several of them, they are read as separate versions.
+func separateVersions(root *XMLNode) []*XMLNode {
+	var divs []*XMLNode
+	for _, div := range versionDivs(root) {
+		if strings.HasPrefix(strings.TrimSpace(div.Attrs["n"]), "urn:cts:") {
+			divs = append(divs, div)
+		}
+	}
+	if len(divs) < 2 {
+		return nil
+	}
+	return divs
+}
+
+//versionURN returns the CTS URN and version type of a file with a single version. The URN is taken from the @n of its version div, preferring the edition, or else from the __cts__.xml of its work. Only if neither has one, it is built from the file name and the namespace of the __cts__.xml or of a CTS URN in the header, e.g. in the refsDecl. A warning is printed when the URN does not match the file name.
+func versionURN(file string, root *XMLNode, document []byte, capitains *CapitainsIndex) (string, string) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	urn, versiontype := "", ""
+	for _, div := range versionDivs(root) {
+		n := strings.TrimSpace(div.Attrs["n"])
+		if strings.HasPrefix(n, "urn:cts:") && (urn == "" || div.Attrs["type"] == "edition" && versiontype != "edition") {
+			urn, versiontype = n, div.Attrs["type"]
+		}
+	}
+	if urn == "" {
+		urn = capitains.versionURN(file, name)
+	}
+	if urn == "" {
+		namespace := firstNonEmpty(capitains.namespace(file), headerNamespace(root, document))
+		if namespace == "" {
+			namespace = defaultNamespace
+			fmt.Println("Warning:", filepath.Base(file), "declares no CTS namespace, using", defaultNamespace)
+		}
+		return namespace + name, versiontype
+	}
+	if parsed, err := ctsurn.Parse(urn); err != nil || parsed.WorkComponent() != name {
+		fmt.Println("Warning:", filepath.Base(file), "declares", urn, "which does not match its file name.")
+	}
+	return urn, versiontype
+}
+
+var namespaceRegExp = regexp.MustCompile(`urn:cts:\pL+:`)
+
+//headerNamespace returns the namespace of the first CTS URN in the teiHeader, e.g. urn:cts:latinLit:
+func headerNamespace(root *XMLNode, document []byte) string {
+	for _, header := range childrenNamed(root, "teiHeader") {
+		if match := namespaceRegExp.Find(document[header.OuterStart:header.OuterEnd]); match != nil {
+			return string(match)
+		}
+	}
+	return ""
+}
+
+//passageURN returns the URN of the passage reference of version. References that do not make a single valid passage, e.g. with a space, a hyphen, brackets or an empty component, are rejected, so that every writer gets the same passages.
+func passageURN(version ctsurn.URN, reference []string) (string, error) {
+	identifier := version.WithPassage(reference...).String()
+	passage, err := ctsurn.Parse(identifier)
+	if err != nil {
+		return identifier, err
+	}
+	if passage.IsRange() || passage.Start.Subreference != "" || passage.Start.Reference != strings.Join(reference, ".") {
+		return identifier, fmt.Errorf("%q is not a single passage", identifier)
+	}
+	return identifier, nil
+}
+
+//passageOf reports whether identifier is a passage of the version or exemplar urn.
+func passageOf(identifier, urn string) bool {
+	passage, err := ctsurn.Parse(identifier)
+	return err == nil && passage.Text().String() == urn
+}
+
+//translationRelations links every passage of a translation to the passage with the same reference in each edition of its work.
+func translationRelations(ctscatalog CTSCatalog, identifiers []string, relations *CiteRelations) {
+	editions := make(map[ctsurn.URN][]ctsurn.URN)
+	translations := make(map[string]bool)
+	for i, v := range ctscatalog.URN {
+		version, err := ctsurn.Parse(v)
+		if err != nil {
+			continue
+		}
+		switch ctscatalog.VersionType[i] {
+		case "edition":
+			work := ctsurn.URN{Namespace: version.Namespace, Textgroup: version.Textgroup, Work: version.Work}
+			editions[work] = append(editions[work], version)
+		case "translation":
+			translations[v] = true
+		}
+	}
+	passages := make(map[string]bool)
+	for _, v := range identifiers {
+		passages[v] = true
+	}
+	for _, identifier := range identifiers {
+		passage, err := ctsurn.Parse(identifier)
+		if err != nil || passage.IsRange() || !translations[passage.Text().String()] {
+			continue
+		}
+		work := ctsurn.URN{Namespace: passage.Namespace, Textgroup: passage.Textgroup, Work: passage.Work}
+		for _, edition := range editions[work] {
+			if target := edition.WithPassage(passage.Start.Reference).String(); passages[target] {
+				relations.add(identifier, translatesVerb, target)
+			}
+		}
+	}
+}